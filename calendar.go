@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/kdb"
+	"github.com/sylms/csv2sql/kdb/calendar"
+)
+
+// 学年暦の YAML を読む
+func readCalendar(path string) (*calendar.Calendar, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	c, err := calendar.Parse(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse calendar %s", path)
+	}
+	return c, nil
+}
+
+// year 年度の学年暦を読む
+func loadCalendar(path string, year int) (*calendar.Year, error) {
+	c, err := readCalendar(path)
+	if err != nil {
+		return nil, err
+	}
+	y, ok := c.ForYear(year)
+	if !ok {
+		return nil, errors.Errorf("%s has no calendar for %d", path, year)
+	}
+	return y, nil
+}
+
+// 年度の学年暦を academic_terms などに保存する
+// その年度の既存のものは消して作りなおす
+func storeCalendar(tx *sqlx.Tx, y *calendar.Year) error {
+	for _, table := range []string{"academic_terms", "academic_holidays", "academic_substitute_days"} {
+		_, err := tx.Exec(`delete from `+table+` where year = $1`, y.Year)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	for name, r := range y.Terms {
+		// calendar.Parse で確認しているためエラーにはならない
+		term, _ := kdb.TermStrToInt(name)
+		_, err := tx.Exec(`insert into academic_terms (year, term, start_date, end_date) values ($1, $2, $3, $4)`,
+			y.Year, term, r.Start.String(), r.End.String())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, d := range y.Holidays {
+		if !y.IsHoliday(d) {
+			// 振替授業日
+			continue
+		}
+		_, err := tx.Exec(`insert into academic_holidays (year, date) values ($1, $2) on conflict do nothing`, y.Year, d.String())
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	for _, s := range y.Substitutes {
+		_, err := tx.Exec(`insert into academic_substitute_days (year, date, day) values ($1, $2, $3)`, y.Year, s.Date.String(), int(s.Day))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
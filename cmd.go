@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// コマンド全体で共有するオプション
+// フラグで指定されなかったものは従来通り SYLMS_* 環境変数の値を使う
+type rootOptions struct {
+	postgresDB       string
+	postgresUser     string
+	postgresPassword string
+	postgresHost     string
+	postgresPort     string
+	year             int
+}
+
+func newRootCmd() *cobra.Command {
+	opts := &rootOptions{}
+
+	cmd := &cobra.Command{
+		Use:           "csv2sql",
+		Short:         "KdB からエクスポートした CSV をデータベースに投入する",
+		Version:       version,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	f := cmd.PersistentFlags()
+	f.StringVar(&opts.postgresDB, "db-name", os.Getenv(envSylmsPostgresDBKey), "データベース名 ($"+envSylmsPostgresDBKey+")")
+	f.StringVar(&opts.postgresUser, "db-user", os.Getenv(envSylmsPostgresUserKey), "データベースのユーザー ($"+envSylmsPostgresUserKey+")")
+	// パスワードはヘルプに表示されないよう、デフォルト値にはせず接続時に環境変数を読む
+	f.StringVar(&opts.postgresPassword, "db-password", "", "データベースのパスワード ($"+envSylmsPostgresPasswordKey+")")
+	f.StringVar(&opts.postgresHost, "db-host", os.Getenv(envSylmsPostgresHostKey), "データベースのホスト ($"+envSylmsPostgresHostKey+")")
+	f.StringVar(&opts.postgresPort, "db-port", os.Getenv(envSylmsPostgresPortKey), "データベースのポート ($"+envSylmsPostgresPortKey+")")
+	f.IntVar(&opts.year, "year", envInt(envSylmsCsvYear), "何年度にエクスポートした CSV であるか ($"+envSylmsCsvYear+")")
+
+	cmd.AddCommand(
+		newImportCmd(opts),
+		newMigrateCmd(opts),
+		newValidateCmd(opts),
+		newDiffCmd(opts),
+		newExportCmd(opts),
+		newHistoryCmd(opts),
+		newCalendarCmd(opts),
+	)
+
+	return cmd
+}
+
+// データベースに接続する
+// 接続情報が足りない場合はどのフラグ（環境変数）が足りないかをエラーで返す
+func (o *rootOptions) openDB() (*sqlx.DB, error) {
+	if o.postgresPassword == "" {
+		o.postgresPassword = os.Getenv(envSylmsPostgresPasswordKey)
+	}
+
+	required := []struct {
+		flag  string
+		env   string
+		value string
+	}{
+		{"db-name", envSylmsPostgresDBKey, o.postgresDB},
+		{"db-user", envSylmsPostgresUserKey, o.postgresUser},
+		{"db-password", envSylmsPostgresPasswordKey, o.postgresPassword},
+		{"db-host", envSylmsPostgresHostKey, o.postgresHost},
+		{"db-port", envSylmsPostgresPortKey, o.postgresPort},
+	}
+	for _, r := range required {
+		if r.value == "" {
+			return nil, errors.Errorf("--%s or %s is not set or empty", r.flag, r.env)
+		}
+	}
+
+	db, err := sqlx.Open("postgres", fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", o.postgresHost, o.postgresPort, o.postgresUser, o.postgresPassword, o.postgresDB))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return db, nil
+}
+
+// 年度が指定されているかを確認する
+func (o *rootOptions) requireYear() (int, error) {
+	if o.year <= 0 {
+		return 0, errors.Errorf("--year or %s is not set or invalid", envSylmsCsvYear)
+	}
+	return o.year, nil
+}
+
+// 環境変数を数値として読む。未設定や数値でない場合は 0 を返す
+func envInt(key string) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return 0
+	}
+	return v
+}
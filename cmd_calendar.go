@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/spf13/cobra"
+)
+
+func newCalendarCmd(opts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "calendar",
+		Short: "学年暦（モジュールの授業期間，休日，振替授業日）を操作する",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "import <calendar.yml>",
+		Short: "学年暦の YAML をデータベースに投入する",
+		Long:  "学年暦の YAML に書かれた年度ごとに academic_terms，academic_holidays，academic_substitute_days を作りなおす",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := readCalendar(args[0])
+			if err != nil {
+				return err
+			}
+
+			db, err := opts.openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			err = execMigrate(db, migrate.Up, 0)
+			if err != nil {
+				return err
+			}
+
+			err = withTx(db, func(tx *sqlx.Tx) error {
+				for i := range c.Years {
+					err := storeCalendar(tx, &c.Years[i])
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			log.Printf("imported calendars for %d years", len(c.Years))
+			return nil
+		},
+	})
+	return cmd
+}
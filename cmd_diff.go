@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newDiffCmd(opts *rootOptions) *cobra.Command {
+	var (
+		inputs  inputOptions
+		verbose bool
+		policy  validationPolicy
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "CSV とデータベースに保存されている科目の差分を表示する",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := validateErrorFormat(policy.errorFormat)
+			if err != nil {
+				return err
+			}
+			err = validateEncoding(inputs.encoding)
+			if err != nil {
+				return err
+			}
+
+			year, err := opts.requireYear()
+			if err != nil {
+				return err
+			}
+
+			courses, stats, err := loadCourses(inputs, year, policy)
+			reportErr := reportRowErrors(cmd.ErrOrStderr(), policy, stats)
+			if reportErr != nil {
+				return reportErr
+			}
+			if err != nil {
+				return err
+			}
+
+			db, err := opts.openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			stored, err := selectCourses(db, year)
+			if err != nil {
+				return err
+			}
+
+			diff := diffCourses(stored, courses)
+
+			out := cmd.OutOrStdout()
+			for _, c := range diff.Added {
+				fmt.Fprintf(out, "+ %s %s\n", c.CourseNumber, c.CourseName)
+			}
+			for _, c := range diff.Removed {
+				fmt.Fprintf(out, "- %s %s\n", c.CourseNumber, c.CourseName)
+			}
+			for _, c := range diff.Changed {
+				columns := []string{}
+				for _, change := range c.Changes {
+					columns = append(columns, change.Column)
+				}
+				fmt.Fprintf(out, "~ %s (%s)\n", c.CourseNumber, strings.Join(columns, ", "))
+				if !verbose {
+					continue
+				}
+				for _, change := range c.Changes {
+					o, err := json.Marshal(change.Old)
+					if err != nil {
+						return errors.WithStack(err)
+					}
+					n, err := json.Marshal(change.New)
+					if err != nil {
+						return errors.WithStack(err)
+					}
+					fmt.Fprintf(out, "    %s: %s -> %s\n", change.Column, o, n)
+				}
+			}
+			fmt.Fprintf(out, "added: %d, removed: %d, changed: %d, unchanged: %d\n", len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+			return nil
+		},
+	}
+	addInputFlags(cmd, &inputs)
+	addValidationFlags(cmd, &policy)
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "変更前後の値も表示する")
+	return cmd
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/sylms/csv2sql/ics"
+)
+
+func newExportCmd(opts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "データベースに保存されている科目を書き出す",
+	}
+
+	cmd.AddCommand(
+		newExportJSONCmd(opts),
+		newExportICSCmd(opts),
+	)
+	return cmd
+}
+
+func newExportJSONCmd(opts *rootOptions) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "json",
+		Short: "指定した年度の科目を JSON で書き出す",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			year, err := opts.requireYear()
+			if err != nil {
+				return err
+			}
+
+			db, err := opts.openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			courses, err := selectCourses(db, year)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return errors.WithStack(enc.Encode(courses))
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "書き出し先のファイル（- は標準出力）")
+	return cmd
+}
+
+func newExportICSCmd(opts *rootOptions) *cobra.Command {
+	var (
+		output        string
+		courseNumber  string
+		calendarPath  string
+		timetablePath string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "ics",
+		Short: "指定した年度の科目の授業日程を iCalendar で書き出す",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if courseNumber == "" {
+				return errors.New("--course is required")
+			}
+			if calendarPath == "" {
+				return errors.New("--calendar is required")
+			}
+			year, err := opts.requireYear()
+			if err != nil {
+				return err
+			}
+
+			academicYear, err := loadCalendar(calendarPath, year)
+			if err != nil {
+				return err
+			}
+			timetable, err := loadTimetable(timetablePath, year)
+			if err != nil {
+				return err
+			}
+			if timetable == nil {
+				return errors.Errorf("no timetable for %d", year)
+			}
+
+			db, err := opts.openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			course, err := selectCourse(db, year, courseNumber)
+			if err != nil {
+				return err
+			}
+			jst, err := time.LoadLocation("Asia/Tokyo")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			events, err := courseEvents(course, academicYear, timetable, jst)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if output != "-" {
+				f, err := os.Create(output)
+				if err != nil {
+					return errors.WithStack(err)
+				}
+				defer f.Close()
+				w = f
+			}
+
+			return errors.WithStack(ics.Write(w, ics.Calendar{
+				ProdID:   icsProdID,
+				Location: jst,
+				Stamp:    now,
+				Events:   events,
+			}))
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "-", "書き出し先のファイル（- は標準出力）")
+	cmd.Flags().StringVar(&courseNumber, "course", "", "科目番号")
+	cmd.Flags().StringVar(&calendarPath, "calendar", "", "モジュールの授業期間と休日を書いた学年暦の YAML")
+	cmd.Flags().StringVar(&timetablePath, "timetable", "", "時限の開始・終了時刻を書いた YAML（省略すると同梱の config/timetable.yml）")
+	return cmd
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func newHistoryCmd(opts *rootOptions) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "history <course_number>",
+		Short: "科目の変更履歴を表示する",
+		Long:  "科目の変更履歴を表示する。--year（$" + envSylmsCsvYear + "）が設定されている場合はその年度のものだけを表示し，--year 0 で全ての年度を表示する",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := opts.openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			revisions, err := selectRevisions(db, args[0], opts.year)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if asJSON {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return errors.WithStack(enc.Encode(revisions))
+			}
+
+			for _, r := range revisions {
+				fmt.Fprintf(out, "%s %d (CSV updated at %s, imported at %s)\n", r.CourseNumber, r.Year, r.CSVUpdatedAt.Format("2006-01-02 15:04:05"), r.CreatedAt.Format("2006-01-02 15:04:05"))
+				columns := []string{}
+				for column := range r.Changes {
+					columns = append(columns, column)
+				}
+				sort.Strings(columns)
+				for _, column := range columns {
+					change := struct {
+						Old json.RawMessage `json:"old"`
+						New json.RawMessage `json:"new"`
+					}{}
+					if err := json.Unmarshal(r.Changes[column], &change); err != nil {
+						return errors.WithStack(err)
+					}
+					fmt.Fprintf(out, "    %s: %s -> %s\n", column, change.Old, change.New)
+				}
+			}
+			if len(revisions) == 0 {
+				fmt.Fprintf(out, "no revisions of %s\n", args[0])
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&asJSON, "json", false, "JSON で出力する")
+	return cmd
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"log"
+
+	"github.com/pkg/errors"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/spf13/cobra"
+)
+
+func newImportCmd(opts *rootOptions) *cobra.Command {
+	var (
+		inputs        inputOptions
+		importOpts    importOptions
+		policy        validationPolicy
+		timetablePath string
+		aliasesPath   string
+		orgsPath      string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "CSV を読み込んでデータベースに投入する",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if importOpts.hardDelete && !importOpts.sync {
+				return errors.New("--hard-delete requires --sync")
+			}
+			err := validateErrorFormat(policy.errorFormat)
+			if err != nil {
+				return err
+			}
+			err = validateEncoding(inputs.encoding)
+			if err != nil {
+				return err
+			}
+
+			year, err := opts.requireYear()
+			if err != nil {
+				return err
+			}
+
+			importOpts.timetable, err = loadTimetable(timetablePath, year)
+			if err != nil {
+				return err
+			}
+			importOpts.instructorAliases, err = loadInstructorAliases(aliasesPath)
+			if err != nil {
+				return err
+			}
+			importOpts.organizations, err = loadOrganizations(orgsPath)
+			if err != nil {
+				return err
+			}
+
+			db, err := opts.openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			err = execMigrate(db, migrate.Up, 0)
+			if err != nil {
+				return err
+			}
+
+			importOpts.importRunID, err = startImportRun(db, year)
+			if err != nil {
+				return err
+			}
+
+			// CSV を読みながらデータベースに流し込む
+			stream := startCourseStream(inputs, year, policy)
+			result, stats, err := importCourses(db, year, stream, importOpts)
+			reportErr := reportRowErrors(cmd.ErrOrStderr(), policy, stats)
+			if reportErr != nil {
+				log.Printf("failed to report errors: %v", reportErr)
+			}
+			finishImportRun(db, importOpts.importRunID, stats, result, err)
+			if err != nil {
+				return err
+			}
+
+			log.Printf("import run %d: inserted: %d, updated: %d, unchanged: %d, deleted: %d, skipped: %d, invalid: %d, unknown credits: %d, revisions: %d", importOpts.importRunID, result.Inserted, result.Updated, result.Unchanged, result.Deleted, stats.Skipped, stats.ParseErrors, stats.UnknownCredits, result.Revisions)
+			log.Println("done")
+			return nil
+		},
+	}
+	addInputFlags(cmd, &inputs)
+	addValidationFlags(cmd, &policy)
+	cmd.Flags().BoolVar(&importOpts.sync, "sync", false, "CSV に含まれない科目をその年度から削除する（論理削除）")
+	cmd.Flags().StringVar(&importOpts.loader, "loader", loaderCopy, "データベースへの流し込み方（copy: COPY, insert: NamedExec による複数行 insert）")
+	cmd.Flags().BoolVar(&importOpts.hardDelete, "hard-delete", false, "--sync で論理削除ではなく実際に削除する")
+	cmd.Flags().StringVar(&timetablePath, "timetable", "", "時限の開始・終了時刻を書いた YAML（省略すると同梱の config/timetable.yml）")
+	cmd.Flags().StringVar(&aliasesPath, "instructor-aliases", "", "担当教員の別名を正式な名前にまとめる YAML")
+	cmd.Flags().StringVar(&orgsPath, "organizations", "", "科目番号の先頭から組織への対応を書いた YAML（省略すると同梱の config/organizations.yml）")
+	return cmd
+}
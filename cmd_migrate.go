@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/spf13/cobra"
+)
+
+func newMigrateCmd(opts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "データベースのマイグレーションを操作する",
+	}
+
+	var upMax int
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "未適用のマイグレーションを適用する",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate(opts, migrate.Up, upMax)
+		},
+	}
+	up.Flags().IntVar(&upMax, "max", 0, "適用するマイグレーションの最大数（0 は全て）")
+
+	var downMax int
+	down := &cobra.Command{
+		Use:   "down",
+		Short: "適用済みのマイグレーションを取り消す",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runMigrate(opts, migrate.Down, downMax)
+		},
+	}
+	down.Flags().IntVar(&downMax, "max", 1, "取り消すマイグレーションの最大数（0 は全て）")
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "マイグレーションの適用状況を表示する",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			db, err := opts.openDB()
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			found, err := migrations.FindMigrations()
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			records, err := migrate.GetMigrationRecords(db.DB, "postgres")
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			applied := map[string]string{}
+			for _, r := range records {
+				applied[r.Id] = r.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "MIGRATION\tAPPLIED")
+			for _, m := range found {
+				appliedAt, ok := applied[m.Id]
+				if !ok {
+					appliedAt = "no"
+				}
+				fmt.Fprintf(w, "%s\t%s\n", m.Id, appliedAt)
+			}
+			return errors.WithStack(w.Flush())
+		},
+	}
+
+	cmd.AddCommand(up, down, status)
+	return cmd
+}
+
+func runMigrate(opts *rootOptions, dir migrate.MigrationDirection, max int) error {
+	db, err := opts.openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return execMigrate(db, dir, max)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func newValidateCmd(opts *rootOptions) *cobra.Command {
+	var (
+		inputs inputOptions
+		policy validationPolicy
+	)
+
+	cmd := &cobra.Command{
+		Use:   "validate",
+		Short: "データベースに接続せずに CSV を検証する",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := validateErrorFormat(policy.errorFormat)
+			if err != nil {
+				return err
+			}
+			err = validateEncoding(inputs.encoding)
+			if err != nil {
+				return err
+			}
+
+			year, err := opts.requireYear()
+			if err != nil {
+				return err
+			}
+
+			// 科目を手元に置く必要はないので数えるだけにする
+			stream := startCourseStream(inputs, year, policy)
+			valid := 0
+			for range stream.courses {
+				valid++
+			}
+			stats, err := stream.wait()
+			reportErr := reportRowErrors(cmd.OutOrStdout(), policy, stats)
+			if reportErr != nil {
+				return reportErr
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%d courses are valid\n", valid)
+			return nil
+		},
+	}
+	addInputFlags(cmd, &inputs)
+	addValidationFlags(cmd, &policy)
+	return cmd
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// --error-format json の標準出力は JSON として読める
+// パーサーが標準出力に何か書くと壊れるので，os.Stdout も同じファイルにする
+func Test_validateCmd_jsonOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "kdb.csv")
+	err := ioutil.WriteFile(input, []byte(kdbCSV(kdbRow("GB10234", "月X", "×", "2022-02-01 10:00:00"))), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.Create(filepath.Join(dir, "out.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	cmd := newRootCmd()
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"validate", "--year", "2022", "-i", input, "--error-format", "json"})
+	if err := cmd.Execute(); err == nil {
+		t.Fatal("validate succeeded with an invalid period")
+	}
+
+	b, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []rowError{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, b)
+	}
+	if len(got) != 1 || got[0].Column != "曜時限" {
+		t.Errorf("errors = %+v, want one error in 曜時限", got)
+	}
+}
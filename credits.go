@@ -0,0 +1,17 @@
+package main
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// 一時テーブルに含まれる科目の credits_numeric を設定する
+// credits（CSV のままの値）は upsert で保存している
+func updateCourseCredits(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`update courses c
+		set credits_numeric = s.credits_numeric
+		from (` + latestStagedQuery(stagingColumns()) + `) s
+		where c.course_number = s.course_number and c.year = s.year
+			and c.credits_numeric is distinct from s.credits_numeric`)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/kdb"
+)
+
+// 読み込んだ CSV 1 つ分の情報
+type inputStats struct {
+	Name   string
+	SHA256 string
+}
+
+// CSV を読み込んだときの件数
+type loadStats struct {
+	Inputs []inputStats
+	// 科目番号がないため読み飛ばした行数
+	Skipped int
+	// 変換できなかった行数
+	ParseErrors int
+	// 変換できなかった行で見つかった問題（最初の maxKeptErrors 件）
+	Errors []rowError
+	// maxKeptErrors を超えたため Errors に残さなかった問題の数
+	OmittedErrors int
+	// reject ファイルに書き出した行数
+	Rejected int
+	// 書き出した reject ファイル
+	RejectFiles []string
+	// 単位数が "?" か数値として読めず，credits_numeric を null にした科目の数
+	UnknownCredits int
+}
+
+// CSV を読み込んで DB 向けの構造体に変換するまでをまとめて行う
+// 複数の CSV が指定された場合はそれぞれ読み込んだものを連結する
+// 全ての科目を手元に置く必要がない場合は startCourseStream を使う
+func loadCourses(inputs inputOptions, year int, policy validationPolicy) ([]Courses, loadStats, error) {
+	stream := startCourseStream(inputs, year, policy)
+	courses := []Courses{}
+	for c := range stream.courses {
+		courses = append(courses, c)
+	}
+	stats, err := stream.wait()
+	if err != nil {
+		return nil, stats, err
+	}
+	return courses, stats, nil
+}
+
+// CSV のもの（KdbExportCSV）から DB 向け（Courses）に構造体を組みなおす
+// 変換できなかったカラムは全て fieldErrors で返す
+func convertRow(row KdbExportCSV, year int) (Courses, []fieldError) {
+	fieldErrors := []fieldError{}
+	fail := func(column, value string, err error) {
+		fieldErrors = append(fieldErrors, fieldError{Column: column, Value: value, Err: err})
+	}
+
+	instructionalType, err := kdb.InstructionalTypeParser(row.InstructionalType)
+	if err != nil {
+		fail("授業方法", row.InstructionalType, err)
+	}
+
+	// 単位数は CSV のまま Credits に保存するので，数値にできなくても行は変換できる
+	// 読めない値は "?" と同じく数値の分からない単位数として credits_numeric を null にする
+	credits, creditsUnknown, err := kdb.CreditsParser(row.Credits)
+	if err != nil {
+		creditsUnknown = true
+	}
+
+	termsInt := []int{}
+	terms, err := kdb.TermParser(row.Term)
+	if err != nil {
+		fail("実施学期", row.Term, err)
+	}
+	for _, term := range terms {
+		termInt, err := kdb.TermStrToInt(term)
+		if err != nil {
+			fail("実施学期", row.Term, err)
+			break
+		}
+		termsInt = append(termsInt, termInt)
+	}
+
+	creditedAuditors, err := kdb.CreditedAuditorsParser(row.CreditedAuditors)
+	if err != nil {
+		fail("科目等履修生申請可否", row.CreditedAuditors, err)
+	}
+
+	csvUpdatedAt, err := kdb.DateParser(row.UpdatedAt)
+	if err != nil {
+		fail("データ更新日", row.UpdatedAt, err)
+	}
+
+	standardRegistrationYear, err := kdb.StandardRegistrationYearParser(row.StandardRegistrationYear)
+	if err != nil {
+		fail("標準履修年次", row.StandardRegistrationYear, err)
+	}
+
+	period, err := kdb.PeriodParser(row.Period)
+	if err != nil {
+		fail("曜時限", row.Period, err)
+	}
+	periods := []kdb.Period{}
+	for _, s := range period {
+		p, err := kdb.ParsePeriod(s)
+		if err != nil {
+			fail("曜時限", row.Period, err)
+			break
+		}
+		periods = append(periods, p)
+	}
+
+	instructor, err := kdb.InstructorParser(row.Instructor)
+	if err != nil {
+		fail("担当教員", row.Instructor, err)
+	}
+
+	// 組織を調べるためだけに使うので，分けられなくても行は読み飛ばさない
+	courseNumber, _ := kdb.ParseCourseNumber(row.CourseNumber)
+
+	return Courses{
+		CourseNumber:             row.CourseNumber,
+		CourseName:               row.CourseName,
+		InstructionalType:        instructionalType,
+		Credits:                  strings.TrimSpace(row.Credits),
+		StandardRegistrationYear: standardRegistrationYear.Strings(),
+		Term:                     termsInt,
+		Period:                   period,
+		Periods:                  periods,
+		Classroom:                row.Classroom,
+		Instructor:               instructor,
+		CourseOverview:           row.CourseOverview,
+		Remarks:                  row.Remarks,
+		CreditedAuditors:         creditedAuditors,
+		ApplicationConditions:    row.ApplicationConditions,
+		AltCourseName:            row.AltCourseName,
+		CourseCode:               row.CourseCode,
+		CourseCodeName:           row.CourseCodeName,
+		CSVUpdatedAt:             csvUpdatedAt,
+		Year:                     year,
+		CreatedAt:                now,
+		UpdatedAt:                now,
+		ParsedCourseNumber:       courseNumber,
+		CreditsNumeric:           credits,
+		CreditsUnknown:           creditsUnknown,
+	}, fieldErrors
+}
+
+// ヘッダーの何番目のカラムが KdbExportCSV のどのフィールドに対応するか
+type kdbColumns struct {
+	// fields[i] はヘッダーの i 番目のカラムに対応するフィールドの番号（対応するものがなければ -1）
+	fields []int
+}
+
+// ヘッダーから kdbColumns を作る
+// KdbExportCSV の csv タグのカラムが 1 つでも欠けていればエラー
+func newKdbColumns(header []string) (kdbColumns, error) {
+	t := reflect.TypeOf(KdbExportCSV{})
+	byName := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		byName[t.Field(i).Tag.Get("csv")] = i
+	}
+
+	columns := kdbColumns{fields: make([]int, len(header))}
+	found := map[int]bool{}
+	for i, name := range header {
+		field, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			columns.fields[i] = -1
+			continue
+		}
+		columns.fields[i] = field
+		found[field] = true
+	}
+
+	missing := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		if !found[i] {
+			missing = append(missing, t.Field(i).Tag.Get("csv"))
+		}
+	}
+	if len(missing) > 0 {
+		return kdbColumns{}, errors.Errorf("missing columns in header: %s", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
+
+// レコードを KdbExportCSV に詰める
+func (c kdbColumns) decode(record []string) KdbExportCSV {
+	row := KdbExportCSV{}
+	v := reflect.ValueOf(&row).Elem()
+	for i, value := range record {
+		if i >= len(c.fields) || c.fields[i] < 0 {
+			continue
+		}
+		v.Field(c.fields[i]).SetString(value)
+	}
+	return row
+}
+
+// フィールド数が合わないレコードからも，分かる範囲で科目番号を取り出す
+func (c kdbColumns) courseNumber(record []string) string {
+	row := c.decode(record)
+	return row.CourseNumber
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var kdbHeader = []string{"科目番号", "科目名", "授業方法", "単位数", "標準履修年次", "実施学期", "曜時限", "教室", "担当教員", "授業概要", "備考", "科目等履修生申請可否", "申請条件", "英語(日本語)科目名", "科目コード", "要件科目名", "データ更新日"}
+
+// テスト用の KdB 形式の CSV を組み立てる
+// rows の各行は kdbHeader と同じ順番の値
+func kdbCSV(rows ...[]string) string {
+	lines := []string{}
+	for _, row := range append([][]string{kdbHeader}, rows...) {
+		lines = append(lines, `"`+strings.Join(row, `","`)+`"`)
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// csv を UTF-8 のファイルに書いて loadCourses で読む
+func loadTestCSV(t *testing.T, csv string, policy validationPolicy) ([]Courses, loadStats, error) {
+	path := filepath.Join(t.TempDir(), "kdb.csv")
+	err := ioutil.WriteFile(path, []byte(csv), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loadCourses(inputOptions{patterns: []string{path}, encoding: encodingUTF8}, 2022, policy)
+}
+
+func kdbRow(courseNumber, period, creditedAuditors, updatedAt string) []string {
+	return []string{courseNumber, "情報科学特論", "1", "2.0", "1・2", "春A", period, "3A202", "筑波 太郎", "", "", creditedAuditors, "", "", "", "", updatedAt}
+}
+
+// row の column の値を value にする
+func withColumn(row []string, column, value string) []string {
+	for i, c := range kdbHeader {
+		if c == column {
+			row[i] = value
+		}
+	}
+	return row
+}
+
+func Test_loadCourses(t *testing.T) {
+	tests := []struct {
+		name        string
+		csv         string
+		policy      validationPolicy
+		wantCourses []string
+		want        []rowError
+		wantErr     bool
+	}{
+		{
+			name: "全ての行を変換できる",
+			csv: kdbCSV(
+				kdbRow("GB10234", "月1,2", "×", "2022-02-01 10:00:00"),
+				kdbRow("", "", "", ""),
+			),
+			wantCourses: []string{"GB10234"},
+			want:        []rowError{},
+		},
+		{
+			name: "変換できない行は全てのカラムのエラーを集めて読み飛ばす",
+			csv: kdbCSV(
+				kdbRow("GB10234", "月1,2", "?", "2022/02/01"),
+				kdbRow("GB10244", "月1,2", "×", "2022-02-01 10:00:00"),
+				kdbRow("GB10254", "月1,2", "○", "2022-02-01 10:00:00"),
+			),
+			policy:      validationPolicy{skipInvalid: true},
+			wantCourses: []string{"GB10244", "GB10254"},
+			want: []rowError{
+				{Input: "kdb.csv", Line: 2, CourseNumber: "GB10234", Column: "科目等履修生申請可否", Value: "?"},
+				{Input: "kdb.csv", Line: 2, CourseNumber: "GB10234", Column: "データ更新日", Value: "2022/02/01"},
+			},
+		},
+		{
+			name: "範囲外の授業方法は読み飛ばす",
+			csv: kdbCSV(
+				withColumn(kdbRow("GB10234", "月1,2", "×", "2022-02-01 10:00:00"), "授業方法", "9"),
+			),
+			policy:      validationPolicy{skipInvalid: true},
+			wantCourses: []string{},
+			want: []rowError{
+				{Input: "kdb.csv", Line: 2, CourseNumber: "GB10234", Column: "授業方法", Value: "9"},
+			},
+		},
+		{
+			name: "読めない単位数でも変換できる",
+			csv: kdbCSV(
+				withColumn(kdbRow("GB10234", "月1,2", "×", "2022-02-01 10:00:00"), "単位数", "1～2"),
+			),
+			wantCourses: []string{"GB10234"},
+			want:        []rowError{},
+		},
+		{
+			name:        "フィールド数が合わない行もエラーとして集める",
+			csv:         kdbCSV() + "\"GB10244\",\"情報科学特論\"\r\n",
+			policy:      validationPolicy{skipInvalid: true},
+			wantCourses: []string{},
+			want: []rowError{
+				{Input: "kdb.csv", Line: 2, CourseNumber: "GB10244"},
+			},
+		},
+		{
+			name: "変換できない行があればエラー",
+			csv: kdbCSV(
+				kdbRow("GB10234", "月1,2", "?", "2022-02-01 10:00:00"),
+			),
+			wantErr: true,
+		},
+		{
+			name: "--max-errors を超えたら打ち切る",
+			csv: kdbCSV(
+				kdbRow("GB10234", "月1,2", "?", "2022/02/01"),
+				kdbRow("GB10244", "月1,2", "×", "2022-02-01 10:00:00"),
+			),
+			policy:  validationPolicy{maxErrors: 1, skipInvalid: true},
+			wantErr: true,
+		},
+		{
+			name:    "ヘッダーにカラムが足りない",
+			csv:     "\"科目番号\",\"科目名\"\r\n\"GB10234\",\"情報科学特論\"\r\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			courses, stats, err := loadTestCSV(t, tt.csv, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadCourses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			gotCourses := []string{}
+			for _, c := range courses {
+				gotCourses = append(gotCourses, c.CourseNumber)
+			}
+			if !reflect.DeepEqual(gotCourses, tt.wantCourses) {
+				t.Errorf("loadCourses() courses = %v, want %v", gotCourses, tt.wantCourses)
+			}
+
+			// メッセージはパーサーのものなので比較しない
+			got := []rowError{}
+			for _, e := range stats.Errors {
+				if e.Message == "" {
+					t.Errorf("loadCourses() error without message: %+v", e)
+				}
+				e.Message = ""
+				e.Input = filepath.Base(e.Input)
+				got = append(got, e)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadCourses() errors = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 単位数は CSV のまま残し，"?" や読めない値は数値にしない
+func Test_loadCourses_credits(t *testing.T) {
+	rows := [][]string{}
+	for i, credits := range []string{"1.5", "?", "1～2", ""} {
+		rows = append(rows, withColumn(kdbRow(fmt.Sprintf("GB1023%d", i), "月1,2", "×", "2022-02-01 10:00:00"), "単位数", credits))
+	}
+	courses, stats, err := loadTestCSV(t, kdbCSV(rows...), validationPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, c := range courses {
+		got = append(got, fmt.Sprintf("%s %q %t", c.Credits, c.CreditsNumeric.String(), c.CreditsUnknown))
+	}
+	want := []string{`1.5 "1.5" false`, `? "" true`, `1～2 "" true`, ` "" false`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("credits = %q, want %q", got, want)
+	}
+	if stats.UnknownCredits != 2 {
+		t.Errorf("UnknownCredits = %d, want 2", stats.UnknownCredits)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// max が 0 の場合は適用できるものを全て適用する
+func execMigrate(db *sqlx.DB, dir migrate.MigrationDirection, max int) error {
+	appliedCount, err := migrate.ExecMax(db.DB, "postgres", migrations, dir, max)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	log.Printf("Applied %v migrations", appliedCount)
+	return nil
+}
+
+// 指定した年度の科目を全て取得する
+// 論理削除されたものは含めない
+func selectCourses(db *sqlx.DB, year int) ([]Courses, error) {
+	return queryCourses(db, "year = $1", year)
+}
+
+// 指定した年度の科目番号の科目を取得する
+func selectCourse(db *sqlx.DB, year int, courseNumber string) (Courses, error) {
+	courses, err := queryCourses(db, "year = $1 and course_number = $2", year, courseNumber)
+	if err != nil {
+		return Courses{}, err
+	}
+	if len(courses) == 0 {
+		return Courses{}, errors.Errorf("course %s is not found in %d", courseNumber, year)
+	}
+	return courses[0], nil
+}
+
+// where に当てはまる論理削除されていない科目を取得する
+func queryCourses(db *sqlx.DB, where string, args ...interface{}) ([]Courses, error) {
+	rows, err := db.Queryx(`select
+			id, course_number, course_name, instructional_type, credits, standard_registration_year, term, period_, classroom, instructor, course_overview, remarks, credited_auditors, application_conditions, alt_course_name, course_code, course_code_name, csv_updated_at, year, created_at, updated_at
+		from courses where `+where+` and deleted_at is null order by course_number, id`, args...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	courses := []Courses{}
+	for rows.Next() {
+		c := Courses{}
+		// 配列のカラムは sqlx がそのまま扱えないため pq.Array を通す
+		err := rows.Scan(
+			&c.ID, &c.CourseNumber, &c.CourseName, &c.InstructionalType, &c.Credits,
+			pq.Array(&c.StandardRegistrationYear), pq.Array(&c.Term), pq.Array(&c.Period),
+			&c.Classroom, pq.Array(&c.Instructor), &c.CourseOverview, &c.Remarks, &c.CreditedAuditors,
+			&c.ApplicationConditions, &c.AltCourseName, &c.CourseCode, &c.CourseCodeName,
+			&c.CSVUpdatedAt, &c.Year, &c.CreatedAt, &c.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		courses = append(courses, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return courses, nil
+}
+
+// courses に投入するカラム
+// id は serial なので含めない
+var courseColumns = []string{
+	"course_number", "course_name", "instructional_type", "credits", "standard_registration_year", "term", "period_", "classroom", "instructor", "course_overview", "remarks", "credited_auditors", "application_conditions", "alt_course_name", "course_code", "course_code_name", "csv_updated_at", "year", "created_at", "updated_at",
+}
+
+// upsert した結果の件数
+type upsertResult struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+}
+
+// 一時テーブルに流し込んだ科目を courses に反映する
+// 科目番号と年度が同じ科目が既にあれば更新し，なければ追加する
+// created_at は最初に追加したときのまま残し，updated_at は内容が変わったときだけ更新する
+// 追加・更新した科目には importRunID を記録する
+func upsert(tx *sqlx.Tx, importRunID int) (upsertResult, error) {
+	var staged, distinct int
+	err := tx.QueryRowx(`select count(*), count(distinct (course_number, year)) from `+stagingTable).Scan(&staged, &distinct)
+	if err != nil {
+		return upsertResult{}, errors.WithStack(err)
+	}
+	if staged != distinct {
+		log.Printf("%d duplicated courses found, the latter ones are used", staged-distinct)
+	}
+
+	result := upsertResult{}
+	err = tx.QueryRowx(`with upserted as (`+upsertQuery()+`)
+		select
+			count(*) filter (where inserted),
+			count(*) filter (where not inserted)
+		from upserted`, importRunID).Scan(&result.Inserted, &result.Updated)
+	if err != nil {
+		return upsertResult{}, errors.WithStack(err)
+	}
+	// 内容が変わらなかった科目は where で更新されないため returning で返ってこない
+	result.Unchanged = distinct - result.Inserted - result.Updated
+	return result, nil
+}
+
+// insert ... on conflict do update の SQL を組み立てる
+// $1 は import_run_id
+// 同じ科目が 1 つの insert 文に 2 回現れると on conflict でエラーになるため，CSV で後に現れたものを使う
+// xmax が 0 の行は今回新しく追加されたもの
+func upsertQuery() string {
+	set := []string{}
+	stored := []string{}
+	excluded := []string{}
+	for _, column := range comparedColumns() {
+		set = append(set, column+" = excluded."+column)
+		stored = append(stored, "courses."+column)
+		excluded = append(excluded, "excluded."+column)
+	}
+	// 論理削除されていた科目が再び現れた場合は deleted_at を元に戻す
+	set = append(set, "updated_at = excluded.updated_at", "deleted_at = null")
+
+	set = append(set, "import_run_id = excluded.import_run_id")
+
+	return `insert into courses (` + joinColumns(courseColumns) + `, import_run_id)
+		select ` + joinColumns(courseColumns) + `, $1::int from (` + latestStagedQuery(courseColumns) + `) s
+		on conflict (course_number, year) do update set ` + strings.Join(set, ", ") + `
+		where courses.deleted_at is not null
+			or (` + strings.Join(stored, ", ") + `) is distinct from (` + strings.Join(excluded, ", ") + `)
+		returning (xmax = 0) as inserted`
+}
+
+// 科目の内容が変わったかどうかの判定に使うカラム
+// 一意制約のキーと，取り込みのたびに変わる日時は含めない
+func comparedColumns() []string {
+	columns := []string{}
+	for _, column := range courseColumns {
+		switch column {
+		case "course_number", "year", "created_at", "updated_at":
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// 一時テーブルから，科目ごとに CSV で最後に現れたものだけを取り出す SQL
+func latestStagedQuery(columns []string) string {
+	return `select distinct on (course_number, year) ` + joinColumns(columns) + `
+		from ` + stagingTable + `
+		order by course_number, year, seq desc`
+}
+
+// year の科目のうち一時テーブルに含まれないものを削除する
+// hardDelete が false の場合は deleted_at を設定して論理削除し，importRunID を記録する
+func syncDeleted(tx *sqlx.Tx, year int, hardDelete bool, importRunID int) (int, error) {
+	// 空の CSV を読んだときに年度の科目が全て消えないようにする
+	var staged int
+	err := tx.QueryRowx(`select count(*) from ` + stagingTable).Scan(&staged)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	if staged == 0 {
+		return 0, errors.New("refusing to sync with no courses")
+	}
+
+	missing := `year = $1 and not exists (
+		select 1 from ` + stagingTable + ` s where s.course_number = courses.course_number and s.year = courses.year
+	)`
+	var res sql.Result
+	if hardDelete {
+		res, err = tx.Exec(`delete from courses where `+missing, year)
+	} else {
+		res, err = tx.Exec(`update courses set deleted_at = $2, updated_at = $2, import_run_id = $3 where deleted_at is null and `+missing, year, now, importRunID)
+	}
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return int(deleted), nil
+}
+
+func joinColumns(columns []string) string {
+	return strings.Join(columns, ", ")
+}
+
+// 列名の前に prefix をつけてつなげる（NamedExec の :column など）
+func joinColumnsWithPrefix(columns []string, prefix string) string {
+	prefixed := []string{}
+	for _, column := range columns {
+		prefixed = append(prefixed, prefix+column)
+	}
+	return strings.Join(prefixed, ", ")
+}
@@ -0,0 +1,472 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sylms/csv2sql/kdb"
+)
+
+// データベースを使うテストの科目の年度
+// 同じ年度の科目がデータベースにあっても，テストはロールバックするので消えない
+const testYear = 1900
+
+// ロールバックするトランザクションの中で f を呼ぶ
+// データベースが設定されていなければスキップする
+func withTestTx(t *testing.T, f func(tx *sqlx.Tx)) {
+	t.Helper()
+	db := openTestDB(t)
+	defer db.Close()
+	tx, err := db.Beginx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	f(tx)
+}
+
+// 一時テーブルを作りなおして courses を流し込む
+func stageCourses(t *testing.T, tx *sqlx.Tx, courses []Courses) {
+	t.Helper()
+	_, err := tx.Exec(`drop table if exists ` + stagingTable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := createStagingTable(tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := (copyLoader{}).load(tx, sendCourses(courses)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// courses.import_run_id が参照する import_runs の行を作る
+func insertTestImportRun(t *testing.T, tx *sqlx.Tx) int {
+	t.Helper()
+	var id int
+	err := tx.QueryRowx(`insert into import_runs (started_at, status, year, tool_version)
+		values ($1, $2, $3, $4) returning id`, now, importRunRunning, testYear, version).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// 科目番号ごとの科目名と論理削除されているか
+type storedCourse struct {
+	CourseName string
+	Deleted    bool
+}
+
+func selectStoredCourses(t *testing.T, tx *sqlx.Tx) map[string]storedCourse {
+	t.Helper()
+	rows, err := tx.Queryx(`select course_number, course_name, deleted_at is not null as deleted from courses where year = $1`, testYear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	stored := map[string]storedCourse{}
+	for rows.Next() {
+		var courseNumber string
+		var c storedCourse
+		if err := rows.Scan(&courseNumber, &c.CourseName, &c.Deleted); err != nil {
+			t.Fatal(err)
+		}
+		stored[courseNumber] = c
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func Test_upsert(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		importRunID := insertTestImportRun(t, tx)
+		courses := syntheticCourses(3, testYear)
+
+		stageCourses(t, tx, courses)
+		got, err := upsert(tx, importRunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (upsertResult{Inserted: 3}); got != want {
+			t.Errorf("first upsert() = %+v, want %+v", got, want)
+		}
+
+		// 内容が変わった科目だけが更新され，created_at は残る
+		createdAt := courses[1].CreatedAt
+		courses[1].CourseName = "変更後"
+		courses[1].CreatedAt = createdAt.AddDate(1, 0, 0)
+		stageCourses(t, tx, courses)
+		got, err = upsert(tx, importRunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (upsertResult{Updated: 1, Unchanged: 2}); got != want {
+			t.Errorf("second upsert() = %+v, want %+v", got, want)
+		}
+
+		var createdChanged bool
+		err = tx.QueryRowx(`select created_at <> $3 from courses where course_number = $1 and year = $2`,
+			courses[1].CourseNumber, testYear, createdAt).Scan(&createdChanged)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if createdChanged {
+			t.Error("created_at is overwritten by upsert")
+		}
+		if name := selectStoredCourses(t, tx)[courses[1].CourseNumber].CourseName; name != "変更後" {
+			t.Errorf("course_name = %q, want %q", name, "変更後")
+		}
+	})
+}
+
+func Test_syncDeleted(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		importRunID := insertTestImportRun(t, tx)
+		courses := syntheticCourses(3, testYear)
+		stageCourses(t, tx, courses)
+		if _, err := upsert(tx, importRunID); err != nil {
+			t.Fatal(err)
+		}
+
+		// CSV から消えた科目は論理削除される
+		stageCourses(t, tx, courses[:2])
+		deleted, err := syncDeleted(tx, testYear, false, importRunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted != 1 {
+			t.Errorf("soft delete: deleted = %d, want 1", deleted)
+		}
+		if c := selectStoredCourses(t, tx)[courses[2].CourseNumber]; !c.Deleted {
+			t.Errorf("%s is not soft-deleted", courses[2].CourseNumber)
+		}
+
+		// 再び現れた科目は論理削除が取り消され，更新として数える
+		stageCourses(t, tx, courses)
+		result, err := upsert(tx, importRunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (upsertResult{Updated: 1, Unchanged: 2}); result != want {
+			t.Errorf("upsert() after soft delete = %+v, want %+v", result, want)
+		}
+		if c := selectStoredCourses(t, tx)[courses[2].CourseNumber]; c.Deleted {
+			t.Errorf("%s is still soft-deleted", courses[2].CourseNumber)
+		}
+
+		// hardDelete なら行ごと削除される
+		stageCourses(t, tx, courses[:1])
+		deleted, err = syncDeleted(tx, testYear, true, importRunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted != 2 {
+			t.Errorf("hard delete: deleted = %d, want 2", deleted)
+		}
+		if stored := selectStoredCourses(t, tx); len(stored) != 1 {
+			t.Errorf("%d courses remain after hard delete, want 1", len(stored))
+		}
+
+		// 空の CSV では削除しない
+		stageCourses(t, tx, []Courses{})
+		if _, err := syncDeleted(tx, testYear, false, importRunID); err == nil {
+			t.Error("syncDeleted() with no staged courses should fail")
+		}
+	})
+}
+
+// 同じ科目が CSV に 2 回現れたら後のものを使う
+func Test_upsert_duplicated(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		importRunID := insertTestImportRun(t, tx)
+		courses := syntheticCourses(2, testYear)
+		courses[0].CourseName = "古い"
+		latest := courses[0]
+		latest.CourseName = "新しい"
+		otherYear := courses[0]
+		otherYear.CourseName = "別の年度"
+		otherYear.Year = testYear + 1
+		stageCourses(t, tx, []Courses{courses[0], courses[1], latest, otherYear})
+
+		got, err := upsert(tx, importRunID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (upsertResult{Inserted: 3}); got != want {
+			t.Errorf("upsert() = %+v, want %+v", got, want)
+		}
+
+		names := []string{}
+		err = tx.Select(&names, `select course_name from courses where course_number = $1 and year in ($2, $3) order by year`,
+			latest.CourseNumber, testYear, testYear+1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"新しい", "別の年度"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("course_name = %q, want %q", names, want)
+		}
+	})
+}
+
+func Test_recordRevisions(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		importRunID := insertTestImportRun(t, tx)
+		courses := syntheticCourses(2, testYear)
+		stageCourses(t, tx, courses)
+		// まだ保存されていない科目の履歴は作らない
+		recorded, err := recordRevisions(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recorded != 0 {
+			t.Errorf("recordRevisions() before insert = %d, want 0", recorded)
+		}
+		if _, err := upsert(tx, importRunID); err != nil {
+			t.Fatal(err)
+		}
+
+		courses[0].CourseName = "変更後"
+		courses[0].UpdatedAt = courses[0].UpdatedAt.AddDate(0, 0, 1)
+		stageCourses(t, tx, courses)
+		recorded, err = recordRevisions(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if recorded != 1 {
+			t.Errorf("recordRevisions() = %d, want 1", recorded)
+		}
+
+		// 変わったカラムだけが記録され，updated_at などは含まれない
+		var changes string
+		err = tx.QueryRowx(`select r.changes::text from course_revisions r join courses c on c.id = r.course_id
+			where c.course_number = $1 and c.year = $2`, courses[0].CourseNumber, testYear).Scan(&changes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]map[string]string{}
+		if err := json.Unmarshal([]byte(changes), &got); err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]map[string]string{"course_name": {"old": "科目 0", "new": "変更後"}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("changes = %v, want %v", got, want)
+		}
+	})
+}
+
+func Test_replaceCoursePeriods(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		importRunID := insertTestImportRun(t, tx)
+		courses := syntheticCourses(2, testYear)
+		courses[1].Period = []string{"集中", "火3"}
+		courses[1].Periods = []kdb.Period{{Kind: kdb.PeriodKindIntensive}, {Day: kdb.DayTuesday, Slot: 3}}
+		stageCourses(t, tx, courses)
+		if _, err := upsert(tx, importRunID); err != nil {
+			t.Fatal(err)
+		}
+		tt, err := loadTimetable("", 2022)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []string{
+			"GB00000 1 1 regular 08:40-09:55",
+			"GB00000 1 2 regular 10:10-11:25",
+			"GB00001 0 0 intensive -",
+			"GB00001 2 3 regular 12:15-13:30",
+		}
+		// 2 回呼んでも重複しない
+		for i := 0; i < 2; i++ {
+			if err := replaceCoursePeriods(tx, tt); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			err = tx.Select(&got, `select concat_ws(' ', c.course_number, coalesce(p.day, 0), coalesce(p.slot, 0), p.kind,
+					coalesce(to_char(p.start_time, 'HH24:MI') || '-' || to_char(p.end_time, 'HH24:MI'), '-'))
+				from course_periods p join courses c on c.id = p.course_id
+				where c.year = $1 order by c.course_number, p.id`, testYear)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("course_periods = %q, want %q", got, want)
+			}
+		}
+	})
+}
+
+// マイグレーションで入れた terms が kdb.Terms と同じ
+func Test_terms_seeded(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		got := []kdb.Term{}
+		err := tx.Select(&got, `select code, label_ja as label, label_en as labelen, season from terms order by sort_order`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, kdb.Terms) {
+			t.Errorf("terms = %v, want %v", got, kdb.Terms)
+		}
+	})
+}
+
+func Test_replaceCourseTerms(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		importRunID := insertTestImportRun(t, tx)
+		courses := syntheticCourses(2, testYear)
+		courses[1].Term = []int{kdb.TermFallVacationCode, kdb.TermAnytimeCode}
+		stageCourses(t, tx, courses)
+		if _, err := upsert(tx, importRunID); err != nil {
+			t.Fatal(err)
+		}
+		if err := replaceCourseTerms(tx); err != nil {
+			t.Fatal(err)
+		}
+
+		// 実施学期が変わったら前の行は残らない
+		courses[0].Term = []int{}
+		stageCourses(t, tx, courses)
+		if _, err := upsert(tx, importRunID); err != nil {
+			t.Fatal(err)
+		}
+		if err := replaceCourseTerms(tx); err != nil {
+			t.Fatal(err)
+		}
+
+		got := []string{}
+		err := tx.Select(&got, `select c.course_number || ' ' || t.label_ja
+			from course_terms ct join courses c on c.id = ct.course_id join terms t on t.code = ct.term
+			where c.year = $1 order by c.course_number, t.sort_order`, testYear)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"GB00001 秋季休業中", "GB00001 随時"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("course_terms = %q, want %q", got, want)
+		}
+
+		// course_term_codes は courses.term と同じ配列を返し，実施学期のない科目も含む
+		codes := []string{}
+		err = tx.Select(&codes, `select c.course_number || ' ' || v.term::text
+			from course_term_codes v join courses c on c.id = v.course_id
+			where c.year = $1 order by c.course_number`, testYear)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"GB00000 {}", "GB00001 {12,13}"}; !reflect.DeepEqual(codes, want) {
+			t.Errorf("course_term_codes = %q, want %q", codes, want)
+		}
+	})
+}
+
+func Test_replaceCourseInstructors(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		importRunID := insertTestImportRun(t, tx)
+		courses := syntheticCourses(2, testYear)
+		// 別名で同じ担当教員が 2 回現れたら最初の位置だけを使う
+		courses[0].Instructor = []string{"筑波 花子", "筑波 太朗", "筑波 太郎"}
+		stageCourses(t, tx, courses)
+		if _, err := upsert(tx, importRunID); err != nil {
+			t.Fatal(err)
+		}
+		aliases := instructorAliases{"筑波 太朗": "筑波 太郎"}
+
+		want := []string{
+			"GB00000 1 筑波 花子",
+			"GB00000 2 筑波 太郎",
+			"GB00001 1 筑波 太郎",
+			"GB00001 2 筑波 花子",
+		}
+		// 2 回呼んでも重複しない
+		for i := 0; i < 2; i++ {
+			if err := replaceCourseInstructors(tx, aliases); err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			err := tx.Select(&got, `select concat_ws(' ', c.course_number, ci.position, i.name)
+				from course_instructors ci join courses c on c.id = ci.course_id join instructors i on i.id = ci.instructor_id
+				where c.year = $1 order by c.course_number, ci.position`, testYear)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("course_instructors = %q, want %q", got, want)
+			}
+		}
+
+		var aliasStored bool
+		if err := tx.QueryRowx(`select exists (select 1 from instructors where name = '筑波 太朗')`).Scan(&aliasStored); err != nil {
+			t.Fatal(err)
+		}
+		if aliasStored {
+			t.Error("alias 筑波 太朗 is stored in instructors")
+		}
+	})
+}
+
+func Test_syncOrganizations(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		importRunID := insertTestImportRun(t, tx)
+		courses := syntheticCourses(2, testYear)
+		courses[1].CourseNumber = "ZZ10001"
+		// convertRow と同じく科目番号を分けておく（一時テーブルの course_prefix, course_level になる）
+		for i := range courses {
+			cn, err := kdb.ParseCourseNumber(courses[i].CourseNumber)
+			if err != nil {
+				t.Fatal(err)
+			}
+			courses[i].ParsedCourseNumber = cn
+		}
+		stageCourses(t, tx, courses)
+		if _, err := upsert(tx, importRunID); err != nil {
+			t.Fatal(err)
+		}
+
+		// 学類が学群より前にあっても保存できる
+		organizations := []organization{{Prefix: "GB", Name: "情報科学類"}, {Prefix: "G", Name: "情報学群"}}
+		if err := syncOrganizations(tx, organizations); err != nil {
+			t.Fatal(err)
+		}
+		var parent string
+		if err := tx.QueryRowx(`select parent_prefix from organizations where prefix = 'GB'`).Scan(&parent); err != nil {
+			t.Fatal(err)
+		}
+		if parent != "G" {
+			t.Errorf("parent_prefix of GB = %q, want G", parent)
+		}
+
+		if err := updateCourseOrganizations(tx); err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		err := tx.Select(&got, `select concat_ws(' ', course_number, coalesce(organization_prefix, '-'), course_level)
+			from courses where year = $1 order by course_number`, testYear)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"GB00000 GB 0", "ZZ10001 - 1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("courses = %q, want %q", got, want)
+		}
+	})
+}
+
+// マイグレーションで入れた instructional_types が kdb.InstructionalType のラベルと同じ
+func Test_instructionalTypes_seeded(t *testing.T) {
+	withTestTx(t, func(tx *sqlx.Tx) {
+		got := []string{}
+		err := tx.Select(&got, `select concat_ws(' / ', code, label_ja, label_en) from instructional_types order by code`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{}
+		for _, it := range kdb.InstructionalTypes() {
+			want = append(want, it.String()+" / "+it.Label()+" / "+it.LabelEn())
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("instructional_types = %q, want %q", got, want)
+		}
+	})
+}
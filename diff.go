@@ -0,0 +1,114 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+// 比較の対象にしないカラム
+// 取り込みのたびに変わるものや、比較のキーになるものは除く
+var diffIgnoredColumns = map[string]bool{
+	"id":         true,
+	"year":       true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// 1 カラム分の変更
+type columnChange struct {
+	Column string      `json:"column"`
+	Old    interface{} `json:"old"`
+	New    interface{} `json:"new"`
+}
+
+// 1 科目分の変更
+type courseChange struct {
+	CourseNumber string         `json:"course_number"`
+	Changes      []columnChange `json:"changes"`
+}
+
+// データベースに保存されている科目と CSV の科目の差分
+type courseDiff struct {
+	Added     []Courses
+	Removed   []Courses
+	Changed   []courseChange
+	Unchanged int
+}
+
+// 科目番号をキーにして stored と incoming を比較する
+func diffCourses(stored, incoming []Courses) courseDiff {
+	storedByNumber := map[string]Courses{}
+	for _, c := range stored {
+		storedByNumber[c.CourseNumber] = c
+	}
+
+	diff := courseDiff{}
+	seen := map[string]bool{}
+	for _, c := range incoming {
+		seen[c.CourseNumber] = true
+		old, ok := storedByNumber[c.CourseNumber]
+		if !ok {
+			diff.Added = append(diff.Added, c)
+			continue
+		}
+		changes := courseColumnChanges(old, c)
+		if len(changes) == 0 {
+			diff.Unchanged++
+			continue
+		}
+		diff.Changed = append(diff.Changed, courseChange{CourseNumber: c.CourseNumber, Changes: changes})
+	}
+
+	for _, c := range stored {
+		if !seen[c.CourseNumber] {
+			diff.Removed = append(diff.Removed, c)
+			// 同じ科目番号が複数保存されていても 1 件として扱う
+			seen[c.CourseNumber] = true
+		}
+	}
+
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return diff.Changed[i].CourseNumber < diff.Changed[j].CourseNumber
+	})
+	return diff
+}
+
+// db タグのついたフィールドを 1 つずつ比較し、値が異なるカラムを返す
+func courseColumnChanges(old, new Courses) []columnChange {
+	changes := []columnChange{}
+
+	oldValue := reflect.ValueOf(old)
+	newValue := reflect.ValueOf(new)
+	t := oldValue.Type()
+	for i := 0; i < t.NumField(); i++ {
+		column := t.Field(i).Tag.Get("db")
+		if column == "" || diffIgnoredColumns[column] {
+			continue
+		}
+
+		o := oldValue.Field(i).Interface()
+		n := newValue.Field(i).Interface()
+		if !columnValueEqual(o, n) {
+			changes = append(changes, columnChange{Column: column, Old: o, New: n})
+		}
+	}
+	return changes
+}
+
+func columnValueEqual(a, b interface{}) bool {
+	// データベースから読んだ時刻はタイムゾーンが異なることがあるため Equal で比較する
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		return ok && at.Equal(bt)
+	}
+
+	// nil と空の slice は同じものとみなす
+	av := reflect.ValueOf(a)
+	bv := reflect.ValueOf(b)
+	if av.Kind() == reflect.Slice && bv.Kind() == reflect.Slice && av.Len() == 0 && bv.Len() == 0 {
+		return true
+	}
+
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+)
+
+const (
+	// 先頭を読んで判定する
+	encodingAuto = "auto"
+	// JIS X 0208 の対応付けでデコードする（0x8160 を U+301C 〜 にするなど）
+	encodingShiftJIS = "shift_jis"
+	// Windows の対応付けでデコードする（①などの機種依存文字を含む）
+	// これまでの KdB の CSV はこれで読んでいる
+	encodingCP932 = "cp932"
+	// BOM があれば取り除く
+	encodingUTF8 = "utf-8"
+)
+
+// 判定に使う先頭のバイト数
+const sniffSize = 64 * 1024
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// デコードした結果に U+FFFD が含まれていた（文字コードの指定が間違っている可能性が高い）
+var errReplacementChar = errors.New("decoding produced U+FFFD, try --encoding")
+
+func validateEncoding(encoding string) error {
+	switch encoding {
+	case encodingAuto, encodingShiftJIS, encodingCP932, encodingUTF8:
+		return nil
+	default:
+		return errors.Errorf("unknown encoding: %s", encoding)
+	}
+}
+
+// r を UTF-8 にデコードするものを返す
+// encoding が encodingAuto の場合は先頭を読んで UTF-8（BOM の有無を問わない）か CP932 かを判定する
+// 返り値の string は実際に使った文字コード
+func newDecodingReader(r io.Reader, encoding string) (io.Reader, string, error) {
+	br := bufio.NewReaderSize(r, sniffSize)
+	head, err := br.Peek(sniffSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", errors.WithStack(err)
+	}
+
+	if encoding == encodingAuto {
+		encoding = sniffEncoding(head, len(head) < sniffSize)
+	}
+
+	checker := &replacementChecker{line: 1}
+	var t transform.Transformer
+	switch encoding {
+	case encodingUTF8:
+		if bytes.HasPrefix(head, utf8BOM) {
+			_, err := br.Discard(len(utf8BOM))
+			if err != nil {
+				return nil, "", errors.WithStack(err)
+			}
+		}
+		t = checker
+	case encodingCP932:
+		t = transform.Chain(japanese.ShiftJIS.NewDecoder(), checker)
+	case encodingShiftJIS:
+		t = transform.Chain(japanese.ShiftJIS.NewDecoder(), runes.Map(cp932ToJIS), checker)
+	default:
+		return nil, "", errors.Errorf("unknown encoding: %s", encoding)
+	}
+	return transform.NewReader(br, t), encoding, nil
+}
+
+// 先頭のバイト列から文字コードを判定する
+// UTF-8 として正しければ UTF-8，そうでなければ CP932 とみなす
+// atEOF でなければ末尾で途切れた文字は判定に使わない
+func sniffEncoding(head []byte, atEOF bool) string {
+	if bytes.HasPrefix(head, utf8BOM) {
+		return encodingUTF8
+	}
+	if !atEOF && len(head) > 0 {
+		if i := lastRuneStart(head); !utf8.FullRune(head[i:]) {
+			head = head[:i]
+		}
+	}
+	if utf8.Valid(head) {
+		return encodingUTF8
+	}
+	return encodingCP932
+}
+
+// 最後の文字が始まる位置
+func lastRuneStart(b []byte) int {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			return i
+		}
+	}
+	return len(b) - 1
+}
+
+// CP932 と JIS X 0208 で対応付けが異なる文字を JIS X 0208 の方に直す
+func cp932ToJIS(r rune) rune {
+	switch r {
+	case '～':
+		return '〜'
+	case '－':
+		return '−'
+	case '∥':
+		return '‖'
+	case '￠':
+		return '¢'
+	case '￡':
+		return '£'
+	case '￢':
+		return '¬'
+	}
+	return r
+}
+
+// cp932ToJIS で直した文字を CP932 の方に戻す
+func jisToCP932(r rune) rune {
+	switch r {
+	case '〜':
+		return '～'
+	case '−':
+		return '－'
+	case '‖':
+		return '∥'
+	case '¢':
+		return '￠'
+	case '£':
+		return '￡'
+	case '¬':
+		return '￢'
+	}
+	return r
+}
+
+// newDecodingReader で encoding として読んだものを，同じ文字コードに戻す Transformer を返す
+// UTF-8 なら nil を返す
+// 戻せない文字があれば Transform がエラーを返す
+func newEncoder(encoding string) (transform.Transformer, error) {
+	switch encoding {
+	case encodingUTF8:
+		return nil, nil
+	case encodingCP932:
+		return japanese.ShiftJIS.NewEncoder(), nil
+	case encodingShiftJIS:
+		return transform.Chain(runes.Map(jisToCP932), japanese.ShiftJIS.NewEncoder()), nil
+	default:
+		return nil, errors.Errorf("unknown encoding: %s", encoding)
+	}
+}
+
+// UTF-8 のバイト列をそのまま通し，U+FFFD や UTF-8 として正しくないバイトがあればエラーにする
+type replacementChecker struct {
+	transform.NopResetter
+	// 次に読む行
+	line int
+}
+
+func (c *replacementChecker) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		if !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+		r, size := utf8.DecodeRune(src[nSrc:])
+		if r == utf8.RuneError {
+			if size == 1 {
+				return nDst, nSrc, errors.Errorf("line %d: invalid UTF-8", c.line)
+			}
+			return nDst, nSrc, errors.Wrapf(errReplacementChar, "line %d", c.line)
+		}
+		if nDst+size > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		copy(dst[nDst:], src[nSrc:nSrc+size])
+		nDst += size
+		nSrc += size
+		if r == '\n' {
+			c.line++
+		}
+	}
+	return nDst, nSrc, nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func Test_newDecodingReader(t *testing.T) {
+	sjis, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte("\"科目名\"\r\n\"①情報～\"\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		input        []byte
+		encoding     string
+		want         string
+		wantEncoding string
+		wantErr      bool
+	}{
+		{
+			name:         "CP932 と判定して機種依存文字も読める",
+			input:        sjis,
+			encoding:     encodingAuto,
+			want:         "\"科目名\"\r\n\"①情報～\"\r\n",
+			wantEncoding: encodingCP932,
+		},
+		{
+			name:         "shift_jis は JIS X 0208 の対応付けにする",
+			input:        sjis,
+			encoding:     encodingShiftJIS,
+			want:         "\"科目名\"\r\n\"①情報〜\"\r\n",
+			wantEncoding: encodingShiftJIS,
+		},
+		{
+			name:         "UTF-8",
+			input:        []byte("\"科目名\"\r\n\"情報\"\r\n"),
+			encoding:     encodingAuto,
+			want:         "\"科目名\"\r\n\"情報\"\r\n",
+			wantEncoding: encodingUTF8,
+		},
+		{
+			name:         "UTF-8 の BOM は取り除く",
+			input:        append([]byte{0xEF, 0xBB, 0xBF}, "\"科目名\"\r\n"...),
+			encoding:     encodingAuto,
+			want:         "\"科目名\"\r\n",
+			wantEncoding: encodingUTF8,
+		},
+		{
+			name:     "UTF-8 として読めなければエラー",
+			input:    sjis,
+			encoding: encodingUTF8,
+			wantErr:  true,
+		},
+		{
+			name:     "デコードして U+FFFD になればエラー",
+			input:    append(append([]byte{}, sjis...), 0x82, '\r', '\n'),
+			encoding: encodingCP932,
+			wantErr:  true,
+		},
+		{
+			name:     "UTF-8 の U+FFFD もエラー",
+			input:    []byte("\"科目名\"\r\n\"\uFFFD\"\r\n"),
+			encoding: encodingAuto,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, encoding, err := newDecodingReader(bytes.NewReader(tt.input), tt.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ioutil.ReadAll(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadAll() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if encoding != tt.wantEncoding {
+				t.Errorf("newDecodingReader() encoding = %s, want %s", encoding, tt.wantEncoding)
+			}
+			if string(got) != tt.want {
+				t.Errorf("newDecodingReader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_sniffEncoding_truncatedRune(t *testing.T) {
+	// 先頭を読んだ範囲の末尾で文字が途切れていても UTF-8 と判定する
+	head := []byte("情報")
+	if got := sniffEncoding(head[:len(head)-1], false); got != encodingUTF8 {
+		t.Errorf("sniffEncoding() = %s, want %s", got, encodingUTF8)
+	}
+	if got := sniffEncoding(head[:len(head)-1], true); got != encodingCP932 {
+		t.Errorf("sniffEncoding() = %s, want %s", got, encodingCP932)
+	}
+}
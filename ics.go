@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/ics"
+	"github.com/sylms/csv2sql/kdb"
+	"github.com/sylms/csv2sql/kdb/calendar"
+	"github.com/sylms/csv2sql/kdb/timetable"
+)
+
+const icsProdID = "-//sylms//csv2sql//JA"
+
+// 科目の曜時限 1 コマごとに，授業期間の間毎週繰り返す予定を作る
+// 休日や授業期間の間の授業のない週は EXDATE で除き，振替授業日は RDATE で足す
+// 集中など曜日のないもの，時間割にない時限，学年暦にない実施学期は警告を出して含めない
+func courseEvents(c Courses, year *calendar.Year, t *timetable.Timetable, loc *time.Location) ([]ics.Event, error) {
+	ranges, missing := year.Ranges(c.Term)
+	for _, term := range missing {
+		log.Printf("warning: %s: no dates in the calendar for term %d", c.CourseNumber, term)
+	}
+	if len(ranges) == 0 {
+		return nil, errors.Errorf("%s: no dates in the calendar for terms %v", c.CourseNumber, c.Term)
+	}
+
+	events := []ics.Event{}
+	seen := map[kdb.Period]bool{}
+	for _, s := range c.Period {
+		p, err := kdb.ParsePeriod(s)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s", c.CourseNumber)
+		}
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		if p.Kind != kdb.PeriodKindRegular {
+			log.Printf("warning: %s: skipping %s, it does not meet weekly", c.CourseNumber, p)
+			continue
+		}
+		st, ok := t.Times(p)
+		if !ok {
+			log.Printf("warning: %s: skipping %s, no time in the timetable", c.CourseNumber, p)
+			continue
+		}
+		m := meetingDates(p.Day, ranges, year)
+		if len(m.dates) == 0 {
+			continue
+		}
+
+		at := func(d calendar.Date) time.Time {
+			return d.At(st.Start.Hour, st.Start.Minute, loc)
+		}
+		e := ics.Event{
+			UID:         fmt.Sprintf("%d-%s-%d-%d@csv2sql", c.Year, c.CourseNumber, p.Day, p.Slot),
+			Summary:     c.CourseName,
+			Location:    c.Classroom,
+			Description: strings.TrimSpace(c.CourseNumber + " " + strings.Join(c.Instructor, "，")),
+			Start:       at(m.dates[0]),
+			End:         m.dates[0].At(st.End.Hour, st.End.Minute, loc),
+		}
+		if m.weekly {
+			e.Until = at(m.dates[len(m.dates)-1])
+		}
+		for _, d := range m.exdates {
+			e.ExDates = append(e.ExDates, at(d))
+		}
+		for _, d := range m.rdates {
+			e.RDates = append(e.RDates, at(d))
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// 1 コマ分の授業日
+type meetings struct {
+	// weekly なら dates の最初から最後まで毎週繰り返す
+	// そうでなければ（振替授業日だけの場合）dates の最初の日だけ
+	dates  []calendar.Date
+	weekly bool
+	// 繰り返しのうち授業のない日
+	exdates []calendar.Date
+	// 繰り返しとは別に授業を行う日
+	rdates []calendar.Date
+}
+
+// 授業期間の中で day の時間割で授業を行う日を，
+// day 曜日の毎週の繰り返しとそれ以外（休日，振替授業日，授業期間の間の週）に分ける
+func meetingDates(day kdb.Day, ranges []calendar.Range, year *calendar.Year) meetings {
+	start, end := ranges[0].Start, ranges[0].End
+	for _, r := range ranges {
+		if r.End.After(end.Time) {
+			end = r.End
+		}
+	}
+
+	m := meetings{}
+	classes := map[string]bool{}
+	regular := []calendar.Date{}
+	for d := start; !d.After(end.Time); d = d.AddDays(1) {
+		if !calendar.InRanges(d, ranges) || year.ClassDay(d) != day {
+			continue
+		}
+		classes[d.String()] = true
+		if calendar.Weekday(d.Weekday()) == day {
+			regular = append(regular, d)
+		} else {
+			m.rdates = append(m.rdates, d)
+		}
+	}
+
+	if len(regular) == 0 {
+		// 振替授業日だけ
+		if len(m.rdates) > 0 {
+			m.dates, m.rdates = m.rdates[:1], m.rdates[1:]
+		}
+		return m
+	}
+
+	m.dates, m.weekly = regular, true
+	for d := regular[0]; !d.After(regular[len(regular)-1].Time); d = d.AddDays(7) {
+		if !classes[d.String()] {
+			m.exdates = append(m.exdates, d)
+		}
+	}
+	return m
+}
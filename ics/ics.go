@@ -0,0 +1,178 @@
+// iCalendar（RFC 5545）を書き出すパッケージ
+//
+// 毎週繰り返す予定だけを扱う．時間帯は夏時間のないもの（Asia/Tokyo など）に限る．
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	dateTimeLayout    = "20060102T150405"
+	utcDateTimeLayout = "20060102T150405Z"
+	// 改行を除いた 1 行の最大のバイト数
+	maxLineOctets = 75
+)
+
+// 予定（VEVENT）
+type Event struct {
+	UID         string
+	Summary     string
+	Location    string
+	Description string
+	// 最初の回の開始・終了時刻
+	Start time.Time
+	End   time.Time
+	// 毎週繰り返す場合の最後の回の開始時刻（ゼロ値なら繰り返さない）
+	Until time.Time
+	// 繰り返しのうち行わない回の開始時刻
+	ExDates []time.Time
+	// 繰り返しとは別に行う回の開始時刻
+	RDates []time.Time
+}
+
+// VCALENDAR
+type Calendar struct {
+	ProdID string
+	// 予定の時刻を書く時間帯（Event の時刻はこの時間帯に直して書く）
+	Location *time.Location
+	// DTSTAMP に使う
+	Stamp  time.Time
+	Events []Event
+}
+
+// c を iCalendar の形式で w に書き出す
+func Write(w io.Writer, c Calendar) error {
+	bw := bufio.NewWriter(w)
+	lw := &lineWriter{w: bw}
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + escapeText(c.ProdID))
+	lw.line("CALSCALE:GREGORIAN")
+	if loc != time.UTC {
+		writeTimezone(lw, loc, c.Stamp)
+	}
+	for _, e := range c.Events {
+		writeEvent(lw, e, loc, c.Stamp)
+	}
+	lw.line("END:VCALENDAR")
+	if lw.err != nil {
+		return lw.err
+	}
+	return bw.Flush()
+}
+
+// 夏時間がない前提で，stamp の時点の時差だけを書く
+func writeTimezone(lw *lineWriter, loc *time.Location, stamp time.Time) {
+	name, offset := stamp.In(loc).Zone()
+	lw.line("BEGIN:VTIMEZONE")
+	lw.line("TZID:" + loc.String())
+	lw.line("BEGIN:STANDARD")
+	lw.line("DTSTART:19700101T000000")
+	lw.line("TZOFFSETFROM:" + formatOffset(offset))
+	lw.line("TZOFFSETTO:" + formatOffset(offset))
+	lw.line("TZNAME:" + escapeText(name))
+	lw.line("END:STANDARD")
+	lw.line("END:VTIMEZONE")
+}
+
+func writeEvent(lw *lineWriter, e Event, loc *time.Location, stamp time.Time) {
+	lw.line("BEGIN:VEVENT")
+	lw.line("UID:" + escapeText(e.UID))
+	lw.line("DTSTAMP:" + stamp.UTC().Format(utcDateTimeLayout))
+	lw.line("DTSTART" + formatDateTime(e.Start, loc))
+	lw.line("DTEND" + formatDateTime(e.End, loc))
+	if !e.Until.IsZero() {
+		// DTSTART に時間帯がある場合 UNTIL は UTC で書く
+		lw.line("RRULE:FREQ=WEEKLY;UNTIL=" + e.Until.UTC().Format(utcDateTimeLayout))
+	}
+	for _, d := range e.ExDates {
+		lw.line("EXDATE" + formatDateTime(d, loc))
+	}
+	for _, d := range e.RDates {
+		lw.line("RDATE" + formatDateTime(d, loc))
+	}
+	lw.line("SUMMARY:" + escapeText(e.Summary))
+	if e.Location != "" {
+		lw.line("LOCATION:" + escapeText(e.Location))
+	}
+	if e.Description != "" {
+		lw.line("DESCRIPTION:" + escapeText(e.Description))
+	}
+	lw.line("END:VEVENT")
+}
+
+// ;TZID=Asia/Tokyo:20220412T084000 または :20220412T084000Z
+func formatDateTime(t time.Time, loc *time.Location) string {
+	if loc == time.UTC {
+		return ":" + t.UTC().Format(utcDateTimeLayout)
+	}
+	return ";TZID=" + loc.String() + ":" + t.In(loc).Format(dateTimeLayout)
+}
+
+// +0900 の形にする
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset/60%60)
+}
+
+// TEXT の値として \ ; , 改行をエスケープする
+func escapeText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return r.Replace(s)
+}
+
+// 1 行ずつ CRLF で書き，75 バイトを超える行は折り返す
+// 最初のエラーを覚えておき，それ以降は何もしない
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+	for first := true; ; first = false {
+		limit, prefix := maxLineOctets, ""
+		if !first {
+			// 続きの行は先頭に空白を置く
+			limit, prefix = maxLineOctets-1, " "
+		}
+		n := foldAt(s, limit)
+		_, lw.err = lw.w.WriteString(prefix + s[:n] + "\r\n")
+		if lw.err != nil {
+			return
+		}
+		s = s[n:]
+		if s == "" {
+			return
+		}
+	}
+}
+
+// s の先頭から limit バイト以内で，UTF-8 の文字の途中にならない位置
+func foldAt(s string, limit int) int {
+	if len(s) <= limit {
+		return len(s)
+	}
+	n := limit
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,55 @@
+package ics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Write(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	start := time.Date(2022, 4, 12, 8, 40, 0, 0, jst)
+	c := Calendar{
+		ProdID:   "-//test//JA",
+		Location: jst,
+		Stamp:    time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC),
+		Events: []Event{{
+			UID:      "a@test",
+			Summary:  "情報科学特論, 演習; その1\n" + strings.Repeat("あ", 30),
+			Start:    start,
+			End:      start.Add(75 * time.Minute),
+			Until:    start.AddDate(0, 0, 14),
+			ExDates:  []time.Time{start.AddDate(0, 0, 7)},
+			RDates:   []time.Time{start.AddDate(0, 0, 3)},
+			Location: "3A204",
+		}},
+	}
+	buf := &bytes.Buffer{}
+	if err := Write(buf, c); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\n",
+		"TZOFFSETTO:+0900\r\n",
+		"DTSTAMP:20220401T000000Z\r\n",
+		"DTSTART;TZID=JST:20220412T084000\r\n",
+		"DTEND;TZID=JST:20220412T095500\r\n",
+		"RRULE:FREQ=WEEKLY;UNTIL=20220425T234000Z\r\n",
+		"EXDATE;TZID=JST:20220419T084000\r\n",
+		"RDATE;TZID=JST:20220415T084000\r\n",
+		`SUMMARY:情報科学特論\, 演習\; その1\nあ`,
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line is longer than %d octets: %q", maxLineOctets, line)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sylms/csv2sql/kdb"
+	"github.com/sylms/csv2sql/kdb/calendar"
+	"github.com/sylms/csv2sql/kdb/timetable"
+)
+
+// 授業期間の間にある休日や授業のない週は EXDATE，振替授業日は RDATE になり，集中は含めない
+func Test_courseEvents(t *testing.T) {
+	c, err := calendar.Parse([]byte(`
+years:
+  - year: 2022
+    terms:
+      春A: {start: 2022-04-12, end: 2022-05-13}
+      春B: {start: 2022-05-23, end: 2022-06-30}
+    holidays: [2022-05-03]
+    substitutes:
+      - {date: 2022-05-12, day: 火}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	year, _ := c.ForYear(2022)
+	tt, err := timetable.Parse([]byte(`
+timetables:
+  - from: 2021
+    slots:
+      1: {start: "8:40", end: "9:55"}
+      2: {start: "10:10", end: "11:25"}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, _ := tt.ForYear(2022)
+	jst := time.FixedZone("JST", 9*60*60)
+
+	course := Courses{
+		CourseNumber: "GB10234",
+		CourseName:   "情報科学特論",
+		Year:         2022,
+		Term:         []int{kdb.TermSpringBCode, kdb.TermSpringACode},
+		Period:       []string{"火1", "火2", "集中"},
+	}
+	events, err := courseEvents(course, year, &table, jst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	e := events[1]
+	if want := time.Date(2022, 4, 12, 10, 10, 0, 0, jst); !e.Start.Equal(want) {
+		t.Errorf("Start = %v, want %v", e.Start, want)
+	}
+	if want := time.Date(2022, 4, 12, 11, 25, 0, 0, jst); !e.End.Equal(want) {
+		t.Errorf("End = %v, want %v", e.End, want)
+	}
+	if want := time.Date(2022, 6, 28, 10, 10, 0, 0, jst); !e.Until.Equal(want) {
+		t.Errorf("Until = %v, want %v", e.Until, want)
+	}
+	// 5/3 は休日，5/17 は春A と春B の間
+	wantEx := []time.Time{time.Date(2022, 5, 3, 10, 10, 0, 0, jst), time.Date(2022, 5, 17, 10, 10, 0, 0, jst)}
+	if len(e.ExDates) != len(wantEx) {
+		t.Fatalf("ExDates = %v, want %v", e.ExDates, wantEx)
+	}
+	for i := range wantEx {
+		if !e.ExDates[i].Equal(wantEx[i]) {
+			t.Errorf("ExDates[%d] = %v, want %v", i, e.ExDates[i], wantEx[i])
+		}
+	}
+	// 5/12 は木曜日だが火曜日の時間割
+	if want := time.Date(2022, 5, 12, 10, 10, 0, 0, jst); len(e.RDates) != 1 || !e.RDates[0].Equal(want) {
+		t.Errorf("RDates = %v, want [%v]", e.RDates, want)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/kdb/timetable"
+)
+
+type importOptions struct {
+	// CSV に含まれない科目を削除する
+	sync bool
+	// sync で削除するときに論理削除ではなく実際に削除する
+	hardDelete bool
+	// 一時テーブルに流し込む方法（loaderCopy, loaderInsert）
+	loader string
+	// import_runs に記録した今回の import
+	importRunID int
+	// course_periods の開始・終了時刻に使う時間割（nil なら保存しない）
+	timetable *timetable.Timetable
+	// course_instructors で使う担当教員の別名
+	instructorAliases instructorAliases
+	// 科目番号の先頭から組織への対応
+	organizations []organization
+}
+
+type importResult struct {
+	upsertResult
+	Deleted   int
+	Revisions int
+}
+
+// stream から読みながら科目を 1 つのトランザクションでデータベースに投入する
+// 読み込みで問題があった場合はロールバックする
+func importCourses(db *sqlx.DB, year int, stream *courseStream, opts importOptions) (importResult, loadStats, error) {
+	result, err := importStream(db, year, stream, opts)
+	// 書き込み側でエラーになった場合に読み込みを止める（読み終えていれば何もしない）
+	stream.stop()
+	stats, _ := stream.wait()
+	return result, stats, err
+}
+
+func importStream(db *sqlx.DB, year int, stream *courseStream, opts importOptions) (importResult, error) {
+	loader, err := newCourseLoader(opts.loader)
+	if err != nil {
+		return importResult{}, err
+	}
+
+	result := importResult{}
+	err = withTx(db, func(tx *sqlx.Tx) error {
+		err := createStagingTable(tx)
+		if err != nil {
+			return err
+		}
+
+		err = loader.load(tx, stream.courses)
+		if err != nil {
+			return err
+		}
+		stats, err := stream.wait()
+		if err != nil {
+			return err
+		}
+		// 読み飛ばした科目まで削除されてしまうため
+		if opts.sync && stats.ParseErrors > 0 {
+			return errors.New("refusing to sync with invalid rows skipped")
+		}
+
+		// upsert で上書きされる前に変更を記録する
+		result.Revisions, err = recordRevisions(tx)
+		if err != nil {
+			return err
+		}
+
+		result.upsertResult, err = upsert(tx, opts.importRunID)
+		if err != nil {
+			return err
+		}
+
+		// ここからは一時テーブルの科目を courses の行（id）に結びつけて関連テーブルやカラムを作るので，upsert の後に行う
+		err = replaceCoursePeriods(tx, opts.timetable)
+		if err != nil {
+			return err
+		}
+		err = replaceCourseTerms(tx)
+		if err != nil {
+			return err
+		}
+		err = replaceCourseInstructors(tx, opts.instructorAliases)
+		if err != nil {
+			return err
+		}
+		// 科目番号に一致する組織を探すので，先に organizations を設定ファイルの内容にする
+		err = syncOrganizations(tx, opts.organizations)
+		if err != nil {
+			return err
+		}
+		err = updateCourseOrganizations(tx)
+		if err != nil {
+			return err
+		}
+		err = updateCourseCredits(tx)
+		if err != nil {
+			return err
+		}
+
+		if opts.sync {
+			result.Deleted, err = syncDeleted(tx, year, opts.hardDelete, opts.importRunID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// ロールバックしたので，途中まで数えた件数は import_runs に記録しない
+		return importResult{}, err
+	}
+	return result, nil
+}
+
+// f がエラーを返した場合はロールバックし，そうでなければコミットする
+func withTx(db *sqlx.DB, f func(tx *sqlx.Tx) error) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	err = f(tx)
+	if err != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			return errors.Wrapf(err, "rollback error: %+v", rollbackErr)
+		}
+		return err
+	}
+
+	return errors.WithStack(tx.Commit())
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	importRunRunning   = "running"
+	importRunSucceeded = "succeeded"
+	importRunFailed    = "failed"
+)
+
+// import の実行を import_runs に記録し始める
+// 取り込みとは別のトランザクションで記録するため，失敗した import も残る
+func startImportRun(db *sqlx.DB, year int) (int, error) {
+	var id int
+	err := db.QueryRowx(`insert into import_runs (started_at, status, year, tool_version)
+		values ($1, $2, $3, $4) returning id`, now, importRunRunning, year, version).Scan(&id)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return id, nil
+}
+
+// import の結果を記録する
+// runErr が nil でなければ失敗として記録する
+// 記録に失敗しても import 自体の結果は変わらないため，ログに残すだけにする
+func finishImportRun(db *sqlx.DB, id int, stats loadStats, result importResult, runErr error) {
+	status := importRunSucceeded
+	var errMessage *string
+	if runErr != nil {
+		status = importRunFailed
+		m := runErr.Error()
+		errMessage = &m
+	}
+
+	paths := []string{}
+	sums := []string{}
+	for _, input := range stats.Inputs {
+		paths = append(paths, input.Name)
+		sums = append(sums, input.SHA256)
+	}
+
+	_, err := db.Exec(`update import_runs set
+			finished_at = $2, status = $3, input_paths = $4, input_sha256 = $5,
+			inserted = $6, updated = $7, unchanged = $8, deleted = $9, skipped = $10, parse_errors = $11, error = $12
+		where id = $1`,
+		id, getDateTimeNow(), status, pq.Array(paths), pq.Array(sums),
+		result.Inserted, result.Updated, result.Unchanged, result.Deleted, stats.Skipped, stats.ParseErrors, errMessage,
+	)
+	if err != nil {
+		log.Printf("failed to record import run %d: %+v", id, errors.WithStack(err))
+	}
+}
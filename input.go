@@ -0,0 +1,131 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// --input を指定しなかったときに読む CSV
+	defaultInputPath = "csv/kdb.csv"
+	// 標準入力から読むことを表す --input の値
+	stdinInputName = "-"
+)
+
+// 読み込む CSV 1 つ分
+// name はログやエラーに表示するためのもの
+type inputSource struct {
+	name string
+	open func() (io.ReadCloser, error)
+}
+
+// 読み込む CSV の指定
+type inputOptions struct {
+	// --input に指定されたもの（resolveInputs で展開する）
+	patterns []string
+	// CSV の文字コード（encodingAuto の場合は判定する）
+	encoding string
+	// 行の変換に使う goroutine の数
+	workers int
+}
+
+func addInputFlags(cmd *cobra.Command, opts *inputOptions) {
+	cmd.Flags().StringArrayVarP(&opts.patterns, "input", "i", []string{defaultInputPath}, "読み込む CSV（ファイル、glob、ディレクトリ、- で標準入力）。複数回指定できる")
+	cmd.Flags().StringVar(&opts.encoding, "encoding", encodingAuto, "CSV の文字コード（auto, shift_jis, cp932, utf-8）")
+	cmd.Flags().IntVar(&opts.workers, "workers", runtime.NumCPU(), "行の変換に使う goroutine の数")
+}
+
+// --input に指定されたものを実際に読むファイルの一覧に展開する
+// ディレクトリの場合は直下の *.csv を、glob の場合はマッチしたものを名前順に読む
+func resolveInputs(patterns []string) ([]inputSource, error) {
+	sources := []inputSource{}
+	seen := map[string]bool{}
+	add := func(path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		sources = append(sources, fileInputSource(path))
+	}
+
+	for _, pattern := range patterns {
+		if pattern == stdinInputName {
+			if seen[stdinInputName] {
+				return nil, errors.New("stdin can be specified only once")
+			}
+			seen[stdinInputName] = true
+			sources = append(sources, inputSource{
+				name: stdinInputName,
+				open: func() (io.ReadCloser, error) {
+					return io.NopCloser(os.Stdin), nil
+				},
+			})
+			continue
+		}
+
+		if isGlobPattern(pattern) {
+			matches, err := filepath.Glob(pattern)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid glob pattern: %s", pattern)
+			}
+			if len(matches) == 0 {
+				return nil, errors.Errorf("no files match %s", pattern)
+			}
+			sort.Strings(matches)
+			for _, m := range matches {
+				add(m)
+			}
+			continue
+		}
+
+		info, err := os.Stat(pattern)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if !info.IsDir() {
+			add(pattern)
+			continue
+		}
+
+		matches, err := filepath.Glob(filepath.Join(pattern, "*.csv"))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if len(matches) == 0 {
+			return nil, errors.Errorf("no csv files in directory %s", pattern)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			add(m)
+		}
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("no input is specified")
+	}
+	return sources, nil
+}
+
+func fileInputSource(path string) inputSource {
+	return inputSource{
+		name: path,
+		open: func() (io.ReadCloser, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, errors.WithStack(err)
+			}
+			return f, nil
+		},
+	}
+}
+
+func isGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_resolveInputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.csv", "a.csv", "note.txt"} {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte{}, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := filepath.Join(dir, "a.csv")
+	b := filepath.Join(dir, "b.csv")
+	note := filepath.Join(dir, "note.txt")
+
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "ファイル",
+			patterns: []string{note},
+			want:     []string{note},
+		},
+		{
+			name:     "ディレクトリは直下の csv を名前順に読む",
+			patterns: []string{dir},
+			want:     []string{a, b},
+		},
+		{
+			name:     "glob",
+			patterns: []string{filepath.Join(dir, "*")},
+			want:     []string{a, b, note},
+		},
+		{
+			name:     "標準入力",
+			patterns: []string{"-", a},
+			want:     []string{"-", a},
+		},
+		{
+			name:     "同じファイルは 1 度だけ読む",
+			patterns: []string{a, dir},
+			want:     []string{a, b},
+		},
+		{
+			name:     "存在しないファイルはエラー",
+			patterns: []string{filepath.Join(dir, "missing.csv")},
+			wantErr:  true,
+		},
+		{
+			name:     "glob にマッチしない場合はエラー",
+			patterns: []string{filepath.Join(dir, "*.tsv")},
+			wantErr:  true,
+		},
+		{
+			name:     "標準入力を 2 回指定するとエラー",
+			patterns: []string{"-", "-"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveInputs(tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("resolveInputs() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			names := []string{}
+			for _, s := range got {
+				names = append(names, s.name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("resolveInputs() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resolveInputs_stdin(t *testing.T) {
+	got, err := resolveInputs([]string{"-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := got[0].open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 標準入力は Close しても閉じない
+	r.Close()
+	if _, err := os.Stdin.Stat(); err != nil {
+		t.Errorf("stdin is closed: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/kdb"
+	"gopkg.in/yaml.v2"
+)
+
+// 担当教員の別名から正式な名前への対応
+// 名前はどちらも kdb.NormalizeInstructorName で正規化したもの
+type instructorAliases map[string]string
+
+// 別名ファイルを読む
+// 正式な名前ごとに別の表記を並べた YAML
+//
+//	筑波 太郎:
+//	  - 筑波 太朗
+//	  - T. Tsukuba
+func loadInstructorAliases(path string) (instructorAliases, error) {
+	aliases := instructorAliases{}
+	if path == "" {
+		return aliases, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	names := map[string][]string{}
+	err = yaml.UnmarshalStrict(b, &names)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse instructor aliases %s", path)
+	}
+
+	canonical := map[string]bool{}
+	for name, others := range names {
+		name = kdb.NormalizeInstructorName(name)
+		canonical[name] = true
+		for _, alias := range others {
+			alias = kdb.NormalizeInstructorName(alias)
+			if alias == name {
+				continue
+			}
+			if other, ok := aliases[alias]; ok && other != name {
+				return nil, errors.Errorf("%s: %q is an alias of both %q and %q", path, alias, other, name)
+			}
+			aliases[alias] = name
+		}
+	}
+	for alias := range aliases {
+		if canonical[alias] {
+			return nil, errors.Errorf("%s: %q is both a name and an alias", path, alias)
+		}
+	}
+	return aliases, nil
+}
+
+// 別名と正式な名前の配列にする
+func (a instructorAliases) arrays() (aliases []string, names []string) {
+	aliases, names = []string{}, []string{}
+	for alias := range a {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		names = append(names, a[alias])
+	}
+	return aliases, names
+}
+
+// 一時テーブルに含まれる科目の担当教員を，別名を正式な名前にしてから course_instructors に入れなおす
+// instructors にまだない名前はここで追加し，position は CSV に書かれた順に 1 から振る
+func replaceCourseInstructors(tx *sqlx.Tx, aliases instructorAliases) error {
+	_, err := tx.Exec(`delete from course_instructors i
+		using courses c, ` + stagingTable + ` s
+		where i.course_id = c.id and c.course_number = s.course_number and c.year = s.year`)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 別名を正式な名前にした担当教員を CSV に書かれた順に並べたもの
+	aliasNames, names := aliases.arrays()
+	staged := `select c.id as course_id, coalesce(a.name, i.name) as name, i.ord
+		from (` + latestStagedQuery(courseColumns) + `) s
+		join courses c on c.course_number = s.course_number and c.year = s.year
+		cross join lateral unnest(s.instructor) with ordinality as i(name, ord)
+		left join unnest($1::text[], $2::text[]) as a(alias, name) on a.alias = i.name
+		where i.name <> ''`
+
+	_, err = tx.Exec(`insert into instructors (name)
+		select distinct name from (`+staged+`) s
+		on conflict (name) do nothing`, pq.Array(aliasNames), pq.Array(names))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// 別名によって同じ担当教員が 2 回現れた場合は最初のものだけを使う
+	_, err = tx.Exec(`insert into course_instructors (course_id, instructor_id, position)
+		select course_id, instructor_id, row_number() over (partition by course_id order by ord)
+		from (
+			select distinct on (s.course_id, ins.id) s.course_id, ins.id as instructor_id, s.ord
+			from (`+staged+`) s
+			join instructors ins on ins.name = s.name
+			order by s.course_id, ins.id, s.ord
+		) d`, pq.Array(aliasNames), pq.Array(names))
+	return errors.WithStack(err)
+}
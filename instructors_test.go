@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_loadInstructorAliases(t *testing.T) {
+	type args struct {
+		yaml string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    instructorAliases
+		wantErr bool
+	}{
+		{
+			name: "aliases are normalized",
+			args: args{yaml: "筑波　太郎:\n  - 筑波  太朗\n  - Ｔ. Tsukuba\n  - 筑波 太郎\n"},
+			want: instructorAliases{"筑波 太朗": "筑波 太郎", "T. Tsukuba": "筑波 太郎"},
+		},
+		{
+			name:    "alias of two names",
+			args:    args{yaml: "筑波 太郎: [筑波]\n筑波 花子: [筑波]\n"},
+			wantErr: true,
+		},
+		{
+			name:    "name is also an alias",
+			args:    args{yaml: "筑波 太郎: [筑波 花子]\n筑波 花子: [筑波]\n"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aliases.yml")
+			if err := ioutil.WriteFile(path, []byte(tt.args.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadInstructorAliases(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadInstructorAliases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadInstructorAliases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// 学年暦（モジュールごとの授業期間，休日，振替授業日）を扱うパッケージ
+//
+// 年度ごとに，実施学期の名前（春A など）から授業期間への対応，休日，
+// 別の曜日の時間割で授業を行う日（振替授業日）を YAML で書く．
+//
+//	years:
+//	  - year: 2022
+//	    terms:
+//	      春A: {start: 2022-04-12, end: 2022-05-20}
+//	      春B: {start: 2022-05-23, end: 2022-06-30}
+//	    holidays:
+//	      - 2022-04-29
+//	    substitutes:
+//	      - {date: 2022-07-18, day: 月}
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"github.com/sylms/csv2sql/kdb"
+	"gopkg.in/yaml.v2"
+)
+
+const dateLayout = "2006-01-02"
+
+// 日付
+// 時刻は持たず，UTC の 0 時として扱う
+type Date struct {
+	time.Time
+}
+
+// "2022-04-12" のような文字列から Date を作る
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date: %q", s)
+	}
+	return Date{t}, nil
+}
+
+// t と同じ日付（t の時間帯での日付）
+func DateOf(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+// n 日後
+func (d Date) AddDays(n int) Date {
+	return Date{d.AddDate(0, 0, n)}
+}
+
+// loc での d の hour 時 minute 分
+func (d Date) At(hour, minute int, loc *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), hour, minute, 0, 0, loc)
+}
+
+func (d *Date) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := ParseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// 期間（Start, End を含む）
+type Range struct {
+	Start Date `yaml:"start"`
+	End   Date `yaml:"end"`
+}
+
+func (r Range) Contains(d Date) bool {
+	return !d.Before(r.Start.Time) && !d.After(r.End.Time)
+}
+
+// 振替授業日
+// Date には Day の時間割で授業を行う
+type Substitute struct {
+	Date Date    `yaml:"date"`
+	Day  kdb.Day `yaml:"day"`
+}
+
+// ある年度の学年暦
+type Year struct {
+	Year int `yaml:"year"`
+	// 実施学期の名前（kdb.TermParser が返すもの）から授業期間
+	Terms       map[string]Range `yaml:"terms"`
+	Holidays    []Date           `yaml:"holidays"`
+	Substitutes []Substitute     `yaml:"substitutes"`
+
+	// 実施学期の番号から授業期間
+	ranges      map[int]Range
+	holidays    map[string]bool
+	substitutes map[string]kdb.Day
+}
+
+// 学年暦の一覧
+type Calendar struct {
+	Years []Year `yaml:"years"`
+}
+
+// YAML を読む
+func Load(r io.Reader) (*Calendar, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+func Parse(b []byte) (*Calendar, error) {
+	c := &Calendar{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, err
+	}
+	seen := map[int]bool{}
+	for i := range c.Years {
+		y := &c.Years[i]
+		if seen[y.Year] {
+			return nil, fmt.Errorf("duplicated calendar for %d", y.Year)
+		}
+		seen[y.Year] = true
+
+		y.ranges = map[int]Range{}
+		for name, r := range y.Terms {
+			code, err := kdb.TermStrToInt(name)
+			if err != nil {
+				return nil, fmt.Errorf("calendar for %d: %w", y.Year, err)
+			}
+			if r.End.Before(r.Start.Time) {
+				return nil, fmt.Errorf("calendar for %d: %s ends before it starts", y.Year, name)
+			}
+			y.ranges[code] = r
+		}
+		y.holidays = map[string]bool{}
+		for _, d := range y.Holidays {
+			y.holidays[d.String()] = true
+		}
+		y.substitutes = map[string]kdb.Day{}
+		for _, sub := range y.Substitutes {
+			if sub.Day < kdb.DayMonday || sub.Day > kdb.DaySunday {
+				return nil, fmt.Errorf("calendar for %d: substitute on %s has no day", y.Year, sub.Date)
+			}
+			if _, ok := y.substitutes[sub.Date.String()]; ok {
+				return nil, fmt.Errorf("calendar for %d: duplicated substitute on %s", y.Year, sub.Date)
+			}
+			y.substitutes[sub.Date.String()] = sub.Day
+		}
+	}
+	sort.Slice(c.Years, func(i, j int) bool {
+		return c.Years[i].Year < c.Years[j].Year
+	})
+	return c, nil
+}
+
+// year 年度の学年暦を返す
+func (c *Calendar) ForYear(year int) (*Year, bool) {
+	for i := range c.Years {
+		if c.Years[i].Year == year {
+			return &c.Years[i], true
+		}
+	}
+	return nil, false
+}
+
+// 春学期・秋学期・通年を含むモジュールに分ける
+// それ以外はそのまま返す
+func Modules(term int) []int {
+	switch term {
+	case kdb.TermSpringCode:
+		return []int{kdb.TermSpringACode, kdb.TermSpringBCode, kdb.TermSpringCCode}
+	case kdb.TermFallCode:
+		return []int{kdb.TermFallACode, kdb.TermFallBCode, kdb.TermFallCCode}
+	case kdb.TermAllCode:
+		return []int{kdb.TermSpringACode, kdb.TermSpringBCode, kdb.TermSpringCCode, kdb.TermFallACode, kdb.TermFallBCode, kdb.TermFallCCode}
+	}
+	return []int{term}
+}
+
+// 実施学期の授業期間を返す
+// 学年暦に書かれていなければ ok が false
+func (y *Year) Range(term int) (r Range, ok bool) {
+	r, ok = y.ranges[term]
+	return r, ok
+}
+
+// 実施学期の授業期間を開始日の順に返す
+// 春学期などはモジュールに分け，学年暦にないものは missing に入れる
+func (y *Year) Ranges(terms []int) (ranges []Range, missing []int) {
+	ranges = []Range{}
+	for _, term := range terms {
+		for _, module := range Modules(term) {
+			r, ok := y.Range(module)
+			if !ok {
+				missing = append(missing, module)
+				continue
+			}
+			ranges = append(ranges, r)
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start.Before(ranges[j].Start.Time)
+	})
+	return ranges, missing
+}
+
+// d が休日か
+// 振替授業日は休日ではない
+func (y *Year) IsHoliday(d Date) bool {
+	if _, ok := y.substitutes[d.String()]; ok {
+		return false
+	}
+	return y.holidays[d.String()]
+}
+
+// d に何曜日の時間割で授業を行うか
+// 休日は kdb.DayNone
+func (y *Year) ClassDay(d Date) kdb.Day {
+	if day, ok := y.substitutes[d.String()]; ok {
+		return day
+	}
+	if y.holidays[d.String()] {
+		return kdb.DayNone
+	}
+	return Weekday(d.Weekday())
+}
+
+// 実施学期が terms，曜時限が periods の科目が d に授業を行うか
+// 集中など曜日のないものは含めない
+func (y *Year) Runs(terms []int, periods []kdb.Period, d Date) bool {
+	ranges, _ := y.Ranges(terms)
+	if !InRanges(d, ranges) {
+		return false
+	}
+	day := y.ClassDay(d)
+	if day == kdb.DayNone {
+		return false
+	}
+	for _, p := range periods {
+		if p.Kind == kdb.PeriodKindRegular && p.Day == day {
+			return true
+		}
+	}
+	return false
+}
+
+// time.Weekday を kdb.Day にする
+func Weekday(w time.Weekday) kdb.Day {
+	if w == time.Sunday {
+		return kdb.DaySunday
+	}
+	return kdb.Day(w)
+}
+
+// d がいずれかの期間に含まれるか
+func InRanges(d Date, ranges []Range) bool {
+	for _, r := range ranges {
+		if r.Contains(d) {
+			return true
+		}
+	}
+	return false
+}
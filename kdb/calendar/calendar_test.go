@@ -0,0 +1,141 @@
+package calendar
+
+import (
+	"testing"
+
+	"github.com/sylms/csv2sql/kdb"
+)
+
+const testCalendar = `
+years:
+  - year: 2022
+    terms:
+      春A: {start: 2022-04-12, end: 2022-05-20}
+      春B: {start: 2022-05-23, end: 2022-06-30}
+    holidays:
+      - 2022-04-29
+`
+
+func Test_Parse(t *testing.T) {
+	type args struct {
+		yaml string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			args: args{yaml: testCalendar},
+		},
+		{
+			name:    "unknown term",
+			args:    args{yaml: "years:\n  - year: 2022\n    terms:\n      春D: {start: 2022-04-12, end: 2022-05-20}\n"},
+			wantErr: true,
+		},
+		{
+			name:    "ends before it starts",
+			args:    args{yaml: "years:\n  - year: 2022\n    terms:\n      春A: {start: 2022-05-20, end: 2022-04-12}\n"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			args:    args{yaml: "years:\n  - year: 2022\n    holidays: [2022-04-31]\n"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown substitute day",
+			args:    args{yaml: "years:\n  - year: 2022\n    substitutes:\n      - {date: 2022-07-18, day: 月曜}\n"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicated year",
+			args:    args{yaml: "years:\n  - year: 2022\n  - year: 2022\n"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.args.yaml))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Year(t *testing.T) {
+	c, err := Parse([]byte(testCalendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.ForYear(2021); ok {
+		t.Error("ForYear(2021) found a calendar")
+	}
+	y, ok := c.ForYear(2022)
+	if !ok {
+		t.Fatal("ForYear(2022) found no calendar")
+	}
+
+	r, ok := y.Range(kdb.TermSpringBCode)
+	if !ok || r.Start.String() != "2022-05-23" || r.End.String() != "2022-06-30" {
+		t.Errorf("Range(春B) = %v-%v, %v", r.Start, r.End, ok)
+	}
+	if _, ok := y.Range(kdb.TermSpringCCode); ok {
+		t.Error("Range(春C) found a range")
+	}
+	d, _ := ParseDate("2022-05-20")
+	if !r.Contains(r.Start) || !r.Contains(r.End) || r.Contains(d) {
+		t.Error("Contains() should include both ends only")
+	}
+	holiday, _ := ParseDate("2022-04-29")
+	if !y.IsHoliday(holiday) || y.IsHoliday(d) {
+		t.Error("IsHoliday() is wrong")
+	}
+}
+
+// 振替授業日はその曜日の時間割で授業を行い，休日は授業を行わない
+func Test_Year_Runs(t *testing.T) {
+	c, err := Parse([]byte(`
+years:
+  - year: 2022
+    terms:
+      春A: {start: 2022-04-12, end: 2022-05-20}
+      春B: {start: 2022-05-23, end: 2022-06-30}
+    holidays: [2022-04-29, 2022-05-02]
+    substitutes:
+      - {date: 2022-05-02, day: 金}
+      - {date: 2022-05-10, day: 金}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, _ := c.ForYear(2022)
+	// 春A の金曜 1 限
+	terms := []int{kdb.TermSpringACode}
+	periods := []kdb.Period{{Day: kdb.DayFriday, Slot: 1}, {Kind: kdb.PeriodKindIntensive}}
+
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2022-04-15", true},  // 金曜日
+		{"2022-04-14", false}, // 木曜日
+		{"2022-04-29", false}, // 金曜日だが休日
+		{"2022-05-02", true},  // 休日だが金曜日の時間割
+		{"2022-05-10", true},  // 火曜日だが金曜日の時間割
+		{"2022-05-27", false}, // 春B
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			d, err := ParseDate(tt.date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := y.Runs(terms, periods, d); got != tt.want {
+				t.Errorf("Runs(%s) = %v, want %v", tt.date, got, tt.want)
+			}
+		})
+	}
+}
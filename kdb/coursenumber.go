@@ -0,0 +1,57 @@
+package kdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 科目番号を分けたもの
+// GB10234 なら Prefix が GB（情報科学類），Level が 1，Serial が 0234
+// 01CH201 のような大学院の科目番号なら Prefix が 01CH，Level が 2，Serial が 01
+type CourseNumber struct {
+	// 開設している組織（学群・学類・専攻など）を表す部分
+	Prefix string
+	// 水準（科目番号で組織の次にある 1 桁）
+	Level int
+	// 組織の中での通し番号
+	Serial string
+}
+
+func (n CourseNumber) String() string {
+	return n.Prefix + strconv.Itoa(n.Level) + n.Serial
+}
+
+// 科目番号をパースする
+// 組織は英字を含む最後の文字まで（英字を含まなければ先頭 2 文字），その次の 1 桁が水準，残りが通し番号
+// 全角の英数字は半角，英字は大文字とみなす
+func ParseCourseNumber(s string) (CourseNumber, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(norm.NFKC.String(s)))
+
+	prefixLen := 0
+	for i := 0; i < len(normalized); i++ {
+		c := normalized[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			prefixLen = i + 1
+		case c >= '0' && c <= '9':
+		default:
+			return CourseNumber{}, fmt.Errorf("unexpected character in course number: %q", s)
+		}
+	}
+	if prefixLen == 0 {
+		prefixLen = 2
+	}
+	// 水準と通し番号が 1 文字以上
+	if len(normalized) < prefixLen+2 {
+		return CourseNumber{}, fmt.Errorf("course number is too short: %q", s)
+	}
+
+	return CourseNumber{
+		Prefix: normalized[:prefixLen],
+		Level:  int(normalized[prefixLen] - '0'),
+		Serial: normalized[prefixLen+1:],
+	}, nil
+}
@@ -0,0 +1,100 @@
+package kdb
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 科目等履修生申請可否
+// データベースでは credited_auditors 型の '0', '1', ... で保存する
+type CreditedAuditors int
+
+const (
+	// ×
+	CreditedAuditorsCross CreditedAuditors = iota
+	// △
+	CreditedAuditorsTriangle
+	// 空
+	CreditedAuditorsEmpty
+	// ○
+	CreditedAuditorsCircle
+)
+
+// KdB の CSV での表記
+var creditedAuditorsLabels = []string{"×", "△", "", "○"}
+
+// NFKC で正規化した後の表記の揺れ
+var creditedAuditorsVariants = map[string]CreditedAuditors{
+	"×": CreditedAuditorsCross,
+	"✕": CreditedAuditorsCross,
+	"✖": CreditedAuditorsCross,
+	"△": CreditedAuditorsTriangle,
+	"▵": CreditedAuditorsTriangle,
+	"":  CreditedAuditorsEmpty,
+	"○": CreditedAuditorsCircle,
+	"〇": CreditedAuditorsCircle,
+	"◯": CreditedAuditorsCircle,
+}
+
+func (c CreditedAuditors) valid() bool {
+	return c >= 0 && int(c) < len(creditedAuditorsLabels)
+}
+
+// KdB の CSV での表記（×, △, ○, 空）
+func (c CreditedAuditors) String() string {
+	if !c.valid() {
+		return fmt.Sprintf("CreditedAuditors(%d)", int(c))
+	}
+	return creditedAuditorsLabels[c]
+}
+
+func (c CreditedAuditors) Value() (driver.Value, error) {
+	if !c.valid() {
+		return nil, fmt.Errorf("invalid credited auditors: %d", int(c))
+	}
+	return strconv.Itoa(int(c)), nil
+}
+
+func (c *CreditedAuditors) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	default:
+		return fmt.Errorf("cannot scan %T into CreditedAuditors", src)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || !CreditedAuditors(n).valid() {
+		return fmt.Errorf("invalid credited auditors: %q", s)
+	}
+	*c = CreditedAuditors(n)
+	return nil
+}
+
+// KdB の CSV での表記の文字列にする
+func (c CreditedAuditors) MarshalJSON() ([]byte, error) {
+	if !c.valid() {
+		return nil, fmt.Errorf("invalid credited auditors: %d", int(c))
+	}
+	return json.Marshal(c.String())
+}
+
+// 科目等履修生申請可否をパースする
+// 全角・半角の ×, △, ○ とその似た文字を受け付ける
+func CreditedAuditorsParser(creditedAuditors string) (CreditedAuditors, error) {
+	s := strings.TrimSpace(norm.NFKC.String(creditedAuditors))
+	c, ok := creditedAuditorsVariants[s]
+	if !ok {
+		return -1, fmt.Errorf("invalid credited auditors: %q", creditedAuditors)
+	}
+	return c, nil
+}
@@ -0,0 +1,70 @@
+package kdb
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 単位数
+// numeric(4,1) に収まるよう 0.1 単位で持つ
+type Credits struct {
+	// 0.1 単位の数（1.5 単位なら 15）
+	Tenths int
+	// 単位数が書かれているか（"?" や空なら false）
+	Valid bool
+}
+
+// "1.5" の形で返す（Valid でなければ空）
+func (c Credits) String() string {
+	if !c.Valid {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d", c.Tenths/10, c.Tenths%10)
+}
+
+// numeric のカラムに保存する（Valid でなければ null）
+func (c Credits) Value() (driver.Value, error) {
+	if !c.Valid {
+		return nil, nil
+	}
+	return c.String(), nil
+}
+
+// 単位数をパースする
+// "?" なら unknown が true，空なら Valid でない Credits を返す
+// 小数は 1 桁まで（0.5, 1.5 など）で，全角の数字は半角とみなす
+func CreditsParser(s string) (credits Credits, unknown bool, err error) {
+	s = strings.TrimSpace(norm.NFKC.String(s))
+	switch s {
+	case "":
+		return Credits{}, false, nil
+	case "?":
+		return Credits{}, true, nil
+	}
+
+	integer, fraction := s, "0"
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		integer, fraction = s[:i], s[i+1:]
+	}
+	if integer == "" || len(integer) > 3 || len(fraction) != 1 || !isDigits(integer) || !isDigits(fraction) {
+		return Credits{}, false, fmt.Errorf("invalid credits: %q", s)
+	}
+	n, err := strconv.Atoi(integer)
+	if err != nil {
+		return Credits{}, false, fmt.Errorf("invalid credits: %q", s)
+	}
+	return Credits{Tenths: n*10 + int(fraction[0]-'0'), Valid: true}, false, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package kdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 授業方法
+// https://www.tsukuba.ac.jp/education/ug-courses-openclass/2021/pdf/1.pdf
+type InstructionalType int
+
+const (
+	// その他
+	InstructionalTypeOther InstructionalType = iota
+	// 講義
+	InstructionalTypeLecture
+	// 演習
+	InstructionalTypeSeminar
+	// 実験・実習・実技
+	InstructionalTypePractical
+	// 講義及び演習
+	InstructionalTypeLectureSeminar
+	// 講義及び実験・実習・実技
+	InstructionalTypeLecturePractical
+	// 演習及び実験・実習・実技
+	InstructionalTypeSeminarPractical
+	// 講義，演習及び実験・実習・実技
+	InstructionalTypeLectureSeminarPractical
+	// 卒業論文・卒業研究等
+	InstructionalTypeGraduationResearch
+)
+
+var instructionalTypeLabels = []struct {
+	ja string
+	en string
+}{
+	{"その他", "Other"},
+	{"講義", "Lecture"},
+	{"演習", "Seminar"},
+	{"実験・実習・実技", "Experiment, Practicum, Practical Skills"},
+	{"講義及び演習", "Lecture and Seminar"},
+	{"講義及び実験・実習・実技", "Lecture and Experiment, Practicum, Practical Skills"},
+	{"演習及び実験・実習・実技", "Seminar and Experiment, Practicum, Practical Skills"},
+	{"講義，演習及び実験・実習・実技", "Lecture, Seminar and Experiment, Practicum, Practical Skills"},
+	{"卒業論文・卒業研究等", "Graduation Thesis, Graduation Research, etc."},
+}
+
+// 授業方法の一覧（データベースの instructional_types テーブル）
+func InstructionalTypes() []InstructionalType {
+	types := make([]InstructionalType, 0, len(instructionalTypeLabels))
+	for i := range instructionalTypeLabels {
+		types = append(types, InstructionalType(i))
+	}
+	return types
+}
+
+func (t InstructionalType) valid() bool {
+	return t >= 0 && int(t) < len(instructionalTypeLabels)
+}
+
+// 日本語のラベル（講義 など）
+func (t InstructionalType) Label() string {
+	if !t.valid() {
+		return t.String()
+	}
+	return instructionalTypeLabels[t].ja
+}
+
+// 英語のラベル（Lecture など）
+func (t InstructionalType) LabelEn() string {
+	if !t.valid() {
+		return t.String()
+	}
+	return instructionalTypeLabels[t].en
+}
+
+// CSV やデータベースの instructional_type 型での値（"1" など）
+func (t InstructionalType) String() string {
+	if !t.valid() {
+		return fmt.Sprintf("InstructionalType(%d)", int(t))
+	}
+	return strconv.Itoa(int(t))
+}
+
+// 授業方法をパースする
+// 空なら InstructionalTypeOther とし，全角の数字は半角とみなす
+func InstructionalTypeParser(s string) (InstructionalType, error) {
+	s = strings.TrimSpace(norm.NFKC.String(s))
+	if s == "" {
+		return InstructionalTypeOther, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("instructional type is not a number: %q", s)
+	}
+	t := InstructionalType(n)
+	if !t.valid() {
+		return 0, fmt.Errorf("instructional type %d is out of range (%d-%d)", n, InstructionalTypeOther, InstructionalTypeGraduationResearch)
+	}
+	return t, nil
+}
@@ -3,10 +3,11 @@ package kdb
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 const (
@@ -23,95 +24,46 @@ const (
 	TermAllCode
 	TermSpringCode
 	TermFallCode
+	TermFallVacationCode // 秋季休業中: 12
+	TermAnytimeCode      // 随時: 13
 )
 
-const (
-	// 科目履修生申請可否
-	// ×
-	CreditedAuditorsCross = iota
-	// △
-	CreditedAuditorsTriangle
-	// 空
-	CreditedAuditorsEmpty
-)
-
-// 開講時期をパースする
-func TermParser(termString string) []string {
+// 担当教員をパースする
+// 「,」「，」「、」で区切り，それぞれ NormalizeInstructorName で正規化する（空のものは除く）
+func InstructorParser(instructors string) ([]string, error) {
 	res := []string{}
-	if termString == "" {
-		return []string{}
-	}
-	var re *regexp.Regexp
-	re = regexp.MustCompile(`(春A|春AA|春AA|春AB|春BA|春AC|春CA|春ABC)`)
-	if re.MatchString(termString) {
-		res = append(res, "春A")
-	}
-	re = regexp.MustCompile(`(春B|春BA|春AB|春BB|春BB|春BC|春CB|春ABC)`)
-	if re.MatchString(termString) {
-		res = append(res, "春B")
-	}
-	re = regexp.MustCompile(`(春C|春CA|春AC|春CB|春BC|春CC|春CC|春ABC)`)
-	if re.MatchString(termString) {
-		res = append(res, "春C")
-	}
-	re = regexp.MustCompile(`(秋A|秋AA|秋AA|秋AB|秋BA|秋AC|秋CA|秋ABC)`)
-	if re.MatchString(termString) {
-		res = append(res, "秋A")
-	}
-	re = regexp.MustCompile(`(秋B|秋BA|秋AB|秋BB|秋BB|秋BC|秋CB|秋ABC)`)
-	if re.MatchString(termString) {
-		res = append(res, "秋B")
-	}
-	re = regexp.MustCompile(`(秋C|秋CA|秋AC|秋CB|秋BC|秋CC|秋CC|秋ABC)`)
-	if re.MatchString(termString) {
-		res = append(res, "秋C")
-	}
-	re = regexp.MustCompile(`(夏季休業中)`)
-	if re.MatchString(termString) {
-		res = append(res, "夏季休業中")
-	}
-	re = regexp.MustCompile(`(春季休業中)`)
-	if re.MatchString(termString) {
-		res = append(res, "春季休業中")
-	}
-	re = regexp.MustCompile(`(通年)`)
-	if re.MatchString(termString) {
-		res = append(res, "通年")
-	}
-	re = regexp.MustCompile(`(春学期)`)
-	if re.MatchString(termString) {
-		res = append(res, "春学期")
-	}
-	re = regexp.MustCompile(`(秋学期)`)
-	if re.MatchString(termString) {
-		res = append(res, "秋学期")
+	for _, name := range strings.FieldsFunc(instructors, isInstructorSeparator) {
+		name = NormalizeInstructorName(name)
+		if name != "" {
+			res = append(res, name)
+		}
 	}
-	return res
+	return res, nil
 }
 
-// 担当教員をパースする
-func InstructorParser(instructors string) ([]string, error) {
-	res := strings.Split(instructors, ",")
-	return res, nil
+func isInstructorSeparator(r rune) bool {
+	return r == ',' || r == '，' || r == '、'
 }
 
-// 科目等履修生をパースする
-func CreditedAuditorsParser(CreditedAuditors string) (int, error) {
-	if CreditedAuditors == "×" {
-		return CreditedAuditorsCross, nil
-	} else if CreditedAuditors == "△" {
-		return CreditedAuditorsTriangle, nil
-	} else if CreditedAuditors == "" {
-		return CreditedAuditorsEmpty, nil
-	} else {
-		return -1, errors.New("invalid input:CreditedAuditors input")
-	}
+// 担当教員の名前を正規化する
+// NFKC で全角の英数字や空白を半角にし，前後の空白を除いて間の空白を 1 つにまとめる
+func NormalizeInstructorName(name string) string {
+	return strings.Join(strings.Fields(norm.NFKC.String(name)), " ")
 }
 
+// KdB の日時は日本時間
+// 行ごとに tzdata を読まないよう 1 度だけ読み込む
+var jst = func() *time.Location {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		return time.FixedZone("JST", 9*60*60)
+	}
+	return loc
+}()
+
 // KdB からエクスポートした CSV に含まれている更新日時カラムのものを time.Time に変換する
 func DateParser(date string) (time.Time, error) {
 	const layout = "2006-01-02 15:04:05"
-	jst, _ := time.LoadLocation("Asia/Tokyo")
 	t, err := time.ParseInLocation(layout, date, jst)
 	if err != nil {
 		return time.Time{}, err
@@ -120,7 +72,7 @@ func DateParser(date string) (time.Time, error) {
 }
 
 // 開講時期を数値に変換
-// 別テーブルなどで管理するのが適切（？）
+// 数値とラベルなどの対応は Terms（データベースでは terms テーブル）にある
 func TermStrToInt(term string) (int, error) {
 	switch term {
 	case "春A":
@@ -145,56 +97,37 @@ func TermStrToInt(term string) (int, error) {
 		return TermSpringCode, nil
 	case "秋学期":
 		return TermFallCode, nil
+	case "秋季休業中":
+		return TermFallVacationCode, nil
+	case "随時":
+		return TermAnytimeCode, nil
 	default:
 		return -1, fmt.Errorf("invalid term string: %s", term)
 	}
 }
 
-// 標準履修年次をパースする
-// 中黒をハイフンとして解釈すると"?" or "int" or "int-int" になるので，"?", "int" はそのまま
-// それ以外は間全てをいれる
-func StandardRegistrationYearParser(yearString string) ([]string, error) {
-	yearString = strings.Replace(yearString, " ", "", -1)
-
-	// 部分文字列を正確に取り出すため
-	yearRune := []rune(yearString)
-
-	if len(yearRune) == 1 {
-		return []string{yearString}, nil
-	}
+// 曜時限の区切りとして取り除く文字と，"-" とみなす文字
+var periodCleaner = strings.NewReplacer(" ", "", "　", "", "ー", "-", "・", "", ",", "")
 
-	minYear, err := strconv.Atoi(string(yearRune[0]))
-	if err != nil {
-		return []string{}, err
-	}
+// 曜日のない曜時限を 1 文字にしたもの（集0 などの 0 は時限がないことを表す）
+var periodWords = strings.NewReplacer("集中", "集0", "応談", "応0", "随時", "随0", "NT", "流0")
 
-	maxYear, err := strconv.Atoi(string(yearRune[2]))
-	if err != nil {
-		return []string{}, err
+// 曜時限の曜日に当たる文字
+func isPeriodDay(r rune) bool {
+	switch r {
+	case '月', '火', '水', '木', '金', '土', '日', '応', '随', '集', '流':
+		return true
 	}
-
-	year := []string{}
-	for i := minYear; i <= maxYear; i++ {
-		year = append(year, strconv.Itoa(i))
-	}
-
-	return year, nil
+	return false
 }
 
 // 時間割をパースする
 func PeriodParser(periodString string) ([]string, error) {
 	period := []string{}
-	periodString = strings.Replace(periodString, " ", "", -1)
-	periodString = strings.Replace(periodString, "　", "", -1)
-	periodString = strings.Replace(periodString, "ー", "-", -1)
-	periodString = strings.Replace(periodString, "・", "", -1)
-	periodString = strings.Replace(periodString, ",", "", -1)
-	periodString = strings.Replace(periodString, "集中", "集0", -1)
-	periodString = strings.Replace(periodString, "応談", "応0", -1)
-	periodString = strings.Replace(periodString, "随時", "随0", -1)
-	periodString = strings.Replace(periodString, "NT", "流0", -1)
-
-	for i := 1; i <= 8; i++ {
+	periodString = periodWords.Replace(periodCleaner.Replace(periodString))
+
+	// 1-3 を 123 にする（"-" がなければ何もしない）
+	for i := 1; i <= 8 && strings.Contains(periodString, "-"); i++ {
 		listPeriod := strconv.Itoa(i)
 		for j := i + 1; j <= 8; j++ {
 			listPeriod = listPeriod + strconv.Itoa(j)
@@ -203,16 +136,20 @@ func PeriodParser(periodString string) ([]string, error) {
 		}
 	}
 
-	for i := 0; i <= 8; i++ {
-		for _, dayOfWeek := range []string{"月", "火", "水", "木", "金", "土", "日", "応", "随", "集", "流"} {
-			beforeStr1 := strconv.Itoa(i) + dayOfWeek
-			beforeStr2 := dayOfWeek + strconv.Itoa(i)
-			afterStr1 := strconv.Itoa(i) + "," + dayOfWeek
-			afterStr2 := dayOfWeek + ":" + strconv.Itoa(i)
-			periodString = strings.Replace(periodString, beforeStr1, afterStr1, -1)
-			periodString = strings.Replace(periodString, beforeStr2, afterStr2, -1)
+	// 時限と曜日の間に ","，曜日と時限の間に ":" を入れる（月1火2 なら 月:1,火:2）
+	var b strings.Builder
+	prev := rune(0)
+	for _, r := range periodString {
+		if prev >= '0' && prev <= '8' && isPeriodDay(r) {
+			b.WriteByte(',')
+		} else if isPeriodDay(prev) && r >= '0' && r <= '8' {
+			b.WriteByte(':')
 		}
+		b.WriteRune(r)
+		prev = r
 	}
+	periodString = b.String()
+
 	if len(periodString) == 0 {
 		return period, nil
 	}
@@ -221,7 +158,6 @@ func PeriodParser(periodString string) ([]string, error) {
 	for _, str := range strList {
 		strList2 := strings.Split(str, ":")
 		if len(strList2) != 2 {
-			fmt.Println("-" + periodString + "-")
 			return nil, errors.New("unexpected period input : " + str)
 		} else {
 			dayOfWeek := strList2[0]
@@ -1,6 +1,7 @@
 package kdb
 
 import (
+	"encoding/json"
 	"reflect"
 	"sort"
 	"testing"
@@ -12,9 +13,10 @@ func Test_TermParser(t *testing.T) {
 		term_string string
 	}
 	tests := []struct {
-		name string
-		args args
-		want []string
+		name    string
+		args    args
+		want    []string
+		wantErr bool
 	}{
 		{
 			name: "spring_A_B",
@@ -33,17 +35,134 @@ func Test_TermParser(t *testing.T) {
 			},
 			want: []string{},
 		},
+		{
+			name: "modules split by spaces",
+			args: args{
+				term_string: "春ABC 秋A",
+			},
+			want: []string{"春A", "春B", "春C", "秋A"},
+		},
+		{
+			name: "modules without separator",
+			args: args{
+				term_string: "春C秋AB",
+			},
+			want: []string{"春C", "秋A", "秋B"},
+		},
+		{
+			name: "full-width letters and space",
+			args: args{
+				term_string: "秋ＡＢ　春Ｃ",
+			},
+			want: []string{"春C", "秋A", "秋B"},
+		},
+		{
+			name: "vacation and module",
+			args: args{
+				term_string: "春C 夏季休業中",
+			},
+			want: []string{"春C", "夏季休業中"},
+		},
+		{
+			name: "semester and all year",
+			args: args{
+				term_string: "春学期・秋学期,通年",
+			},
+			want: []string{"通年", "春学期", "秋学期"},
+		},
+		{
+			name: "duplicated modules",
+			args: args{
+				term_string: "春AA 春A",
+			},
+			want: []string{"春A"},
+		},
+		{
+			name: "fall vacation and anytime",
+			args: args{
+				term_string: "随時 秋季休業中 秋C",
+			},
+			want: []string{"秋C", "秋季休業中", "随時"},
+		},
+		{
+			name: "unknown token",
+			args: args{
+				term_string: "春A 冬学期",
+			},
+			wantErr: true,
+		},
+		{
+			name: "season without module",
+			args: args{
+				term_string: "春",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown module",
+			args: args{
+				term_string: "春D",
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := TermParser(tt.args.term_string)
-			if !reflect.DeepEqual(got, tt.want) {
+			got, err := TermParser(tt.args.term_string)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TermParser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("TermParser() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func Test_InstructorParser(t *testing.T) {
+	type args struct {
+		instructors string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "comma",
+			args: args{instructors: "筑波 太郎,筑波 花子"},
+			want: []string{"筑波 太郎", "筑波 花子"},
+		},
+		{
+			name: "full-width separators and spaces",
+			args: args{instructors: "筑波　太郎， 筑波  花子、Ｊｏｈｎ Ｓｍｉｔｈ"},
+			want: []string{"筑波 太郎", "筑波 花子", "John Smith"},
+		},
+		{
+			name: "empty names are dropped",
+			args: args{instructors: " , 筑波 太郎,"},
+			want: []string{"筑波 太郎"},
+		},
+		{
+			name: "empty string -> empty []string",
+			args: args{instructors: ""},
+			want: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InstructorParser(tt.args.instructors)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("InstructorParser() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_creditedAuditorsParser(t *testing.T) {
 	type args struct {
 		CreditedAuditors string
@@ -51,7 +170,7 @@ func Test_creditedAuditorsParser(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    args
-		want    int
+		want    CreditedAuditors
 		wantErr bool
 	}{
 		{
@@ -78,6 +197,43 @@ func Test_creditedAuditorsParser(t *testing.T) {
 			want:    0,
 			wantErr: false,
 		},
+		{
+			name: "maru",
+			args: args{
+				CreditedAuditors: "○",
+			},
+			want: CreditedAuditorsCircle,
+		},
+		{
+			name: "half-width maru",
+			args: args{
+				CreditedAuditors: "\uffee",
+			},
+			want: CreditedAuditorsCircle,
+		},
+		{
+			name: "cross variant with spaces",
+			args: args{
+				CreditedAuditors: " ✕ ",
+			},
+			want: CreditedAuditorsCross,
+		},
+		{
+			name: "ascii x is not a cross",
+			args: args{
+				CreditedAuditors: "ｘ",
+			},
+			want:    -1,
+			wantErr: true,
+		},
+		{
+			name: "ascii o is not a circle",
+			args: args{
+				CreditedAuditors: "O",
+			},
+			want:    -1,
+			wantErr: true,
+		},
 		{
 			name: "invalid",
 			args: args{
@@ -101,6 +257,38 @@ func Test_creditedAuditorsParser(t *testing.T) {
 	}
 }
 
+// データベースと JSON の値から元に戻せる
+func Test_CreditedAuditors_marshal(t *testing.T) {
+	for _, c := range []CreditedAuditors{CreditedAuditorsCross, CreditedAuditorsTriangle, CreditedAuditorsEmpty, CreditedAuditorsCircle} {
+		v, err := c.Value()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var scanned CreditedAuditors
+		if err := scanned.Scan([]byte(v.(string))); err != nil || scanned != c {
+			t.Errorf("Scan(%q) = %v, %v, want %v", v, scanned, err, c)
+		}
+
+		b, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `"` + c.String() + `"`; string(b) != want {
+			t.Errorf("json.Marshal(%d) = %s, want %s", int(c), b, want)
+		}
+		if parsed, err := CreditedAuditorsParser(c.String()); err != nil || parsed != c {
+			t.Errorf("CreditedAuditorsParser(%q) = %v, %v, want %v", c.String(), parsed, err, c)
+		}
+	}
+	if _, err := CreditedAuditors(4).Value(); err == nil {
+		t.Error("Value() of an invalid value should fail")
+	}
+	var c CreditedAuditors
+	if err := c.Scan("4"); err == nil {
+		t.Error("Scan(\"4\") should fail")
+	}
+}
+
 func Test_standardRegistrationYearParser(t *testing.T) {
 	type args struct {
 		yearString string
@@ -168,14 +356,89 @@ func Test_standardRegistrationYearParser(t *testing.T) {
 			want:    []string{},
 			wantErr: true,
 		},
+		{
+			name: "全角の数字と中黒",
+			args: args{
+				yearString: "１・２",
+			},
+			want: []string{"1", "2"},
+		},
+		{
+			name: "全角の？",
+			args: args{
+				yearString: "？",
+			},
+			want: []string{"?"},
+		},
+		{
+			name: "カンマで並べる",
+			args: args{
+				yearString: "1,3",
+			},
+			want: []string{"1", "3"},
+		},
+		{
+			name: "範囲と読点",
+			args: args{
+				yearString: "1・2、4 - 5",
+			},
+			want: []string{"1", "2", "4", "5"},
+		},
+		{
+			name: "以上",
+			args: args{
+				yearString: "2以上",
+			},
+			want: []string{"2", "3", "4", "5", "6"},
+		},
+		{
+			name: "空は年次なし",
+			args: args{
+				yearString: "",
+			},
+			want: []string{},
+		},
+		{
+			name: "2 文字でも panic しない",
+			args: args{
+				yearString: "1・",
+			},
+			want:    []string{},
+			wantErr: true,
+		},
+		{
+			name: "範囲外の年次",
+			args: args{
+				yearString: "1-7",
+			},
+			want:    []string{},
+			wantErr: true,
+		},
+		{
+			name: "逆順の範囲",
+			args: args{
+				yearString: "3-1",
+			},
+			want:    []string{},
+			wantErr: true,
+		},
+		{
+			name: "? と年次を混ぜられない",
+			args: args{
+				yearString: "1,?",
+			},
+			want:    []string{},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := StandardRegistrationYearParser(tt.args.yearString)
+			years, err := StandardRegistrationYearParser(tt.args.yearString)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("standardRegistrationYearParser() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			got := years.Strings()
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("standardRegistrationYearParser() = %v, want %v", got, tt.want)
 			}
@@ -391,3 +654,227 @@ func Test_DateParser(t *testing.T) {
 		})
 	}
 }
+
+func Test_PeriodsParser(t *testing.T) {
+	type args struct {
+		periodString string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []Period
+		wantErr bool
+	}{
+		{
+			name: "曜日と時限",
+			args: args{
+				periodString: "月1,2 木3",
+			},
+			want: []Period{
+				{Day: DayMonday, Slot: 1},
+				{Day: DayMonday, Slot: 2},
+				{Day: DayThursday, Slot: 3},
+			},
+		},
+		{
+			name: "集中",
+			args: args{
+				periodString: "集中",
+			},
+			want: []Period{{Kind: PeriodKindIntensive}},
+		},
+		{
+			name: "応談・随時・NT",
+			args: args{
+				periodString: "応談 随時 NT",
+			},
+			want: []Period{{Kind: PeriodKindByAppointment}, {Kind: PeriodKindAnytime}, {Kind: PeriodKindNT}},
+		},
+		{
+			name: "空",
+			args: args{
+				periodString: "",
+			},
+			want: []Period{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PeriodsParser(tt.args.periodString)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PeriodsParser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			// slice の順序は重視していないため文字列にしてソートして両方あわせる
+			if !reflect.DeepEqual(sortedPeriods(got), sortedPeriods(tt.want)) {
+				t.Errorf("PeriodsParser() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func sortedPeriods(periods []Period) []string {
+	strs := []string{}
+	for _, p := range periods {
+		strs = append(strs, p.String())
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+func Test_ParsePeriod(t *testing.T) {
+	for _, s := range []string{"月1", "日8", "集中", "NT", "集中1"} {
+		p, err := ParsePeriod(s)
+		if err != nil {
+			t.Errorf("ParsePeriod(%q) error = %v", s, err)
+			continue
+		}
+		if p.String() != s {
+			t.Errorf("ParsePeriod(%q).String() = %q", s, p.String())
+		}
+	}
+	for _, s := range []string{"", "月", "月x", "祝1", "月0"} {
+		if _, err := ParsePeriod(s); err == nil {
+			t.Errorf("ParsePeriod(%q) error = nil", s)
+		}
+	}
+}
+
+// Terms の番号は TermStrToInt と同じ
+func Test_Terms(t *testing.T) {
+	for _, term := range Terms {
+		code, err := TermStrToInt(term.Label)
+		if err != nil || code != term.Code {
+			t.Errorf("TermStrToInt(%q) = %d, %v, want %d", term.Label, code, err, term.Code)
+		}
+	}
+}
+
+func Test_ParseCourseNumber(t *testing.T) {
+	type args struct {
+		s string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    CourseNumber
+		wantErr bool
+	}{
+		{
+			name: "undergraduate",
+			args: args{s: "GB10234"},
+			want: CourseNumber{Prefix: "GB", Level: 1, Serial: "0234"},
+		},
+		{
+			name: "graduate",
+			args: args{s: "01CH201"},
+			want: CourseNumber{Prefix: "01CH", Level: 2, Serial: "01"},
+		},
+		{
+			name: "digits only",
+			args: args{s: "1227101"},
+			want: CourseNumber{Prefix: "12", Level: 2, Serial: "7101"},
+		},
+		{
+			name: "full-width and lower case",
+			args: args{s: "ｇｂ１０２３４"},
+			want: CourseNumber{Prefix: "GB", Level: 1, Serial: "0234"},
+		},
+		{
+			name:    "no level",
+			args:    args{s: "GB1"},
+			wantErr: true,
+		},
+		{
+			name:    "letter at the end",
+			args:    args{s: "GB1023A"},
+			wantErr: true,
+		},
+		{
+			name:    "unexpected character",
+			args:    args{s: "GB-10234"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCourseNumber(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCourseNumber() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseCourseNumber() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_CreditsParser(t *testing.T) {
+	type args struct {
+		s string
+	}
+	tests := []struct {
+		name        string
+		args        args
+		want        string
+		wantUnknown bool
+		wantErr     bool
+	}{
+		{name: "decimal", args: args{s: "2.0"}, want: "2.0"},
+		{name: "half", args: args{s: "0.5"}, want: "0.5"},
+		{name: "integer", args: args{s: "1"}, want: "1.0"},
+		{name: "full-width", args: args{s: "１．５"}, want: "1.5"},
+		{name: "unknown", args: args{s: "?"}, wantUnknown: true},
+		{name: "full-width unknown", args: args{s: "？"}, wantUnknown: true},
+		{name: "empty", args: args{s: " "}},
+		{name: "two fraction digits", args: args{s: "1.25"}, wantErr: true},
+		{name: "too large", args: args{s: "1000"}, wantErr: true},
+		{name: "negative", args: args{s: "-1.0"}, wantErr: true},
+		{name: "not a number", args: args{s: "二"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, unknown, err := CreditsParser(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreditsParser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.String() != tt.want || got.Valid != (tt.want != "") || unknown != tt.wantUnknown {
+				t.Errorf("CreditsParser() = %q (valid %v), %v, want %q, %v", got, got.Valid, unknown, tt.want, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+func Test_InstructionalTypeParser(t *testing.T) {
+	type args struct {
+		s string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    InstructionalType
+		wantErr bool
+	}{
+		{name: "lecture", args: args{s: "1"}, want: InstructionalTypeLecture},
+		{name: "graduation research", args: args{s: "8"}, want: InstructionalTypeGraduationResearch},
+		{name: "full-width", args: args{s: "３"}, want: InstructionalTypePractical},
+		{name: "empty is other", args: args{s: ""}, want: InstructionalTypeOther},
+		{name: "out of range", args: args{s: "9"}, wantErr: true},
+		{name: "negative", args: args{s: "-1"}, wantErr: true},
+		{name: "not a number", args: args{s: "講義"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := InstructionalTypeParser(tt.args.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("InstructionalTypeParser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("InstructionalTypeParser() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := InstructionalTypePractical.Label(); got != "実験・実習・実技" {
+		t.Errorf("Label() = %q", got)
+	}
+}
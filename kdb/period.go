@@ -0,0 +1,163 @@
+package kdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 曜日
+// 1 が月曜日で，PostgreSQL の extract(isodow from ...) と同じ値になる
+type Day int
+
+const (
+	// 曜日がない（集中など）
+	DayNone Day = iota
+	DayMonday
+	DayTuesday
+	DayWednesday
+	DayThursday
+	DayFriday
+	DaySaturday
+	DaySunday
+)
+
+var dayLabels = []string{"", "月", "火", "水", "木", "金", "土", "日"}
+
+func (d Day) String() string {
+	if d < DayNone || int(d) >= len(dayLabels) {
+		return fmt.Sprintf("Day(%d)", int(d))
+	}
+	return dayLabels[d]
+}
+
+// "月" などの曜日を Day にする
+func ParseDay(s string) (Day, error) {
+	for d := DayMonday; d <= DaySunday; d++ {
+		if s == dayLabels[d] {
+			return d, nil
+		}
+	}
+	return DayNone, fmt.Errorf("unexpected day: %q", s)
+}
+
+func (d *Day) UnmarshalText(text []byte) error {
+	parsed, err := ParseDay(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// 曜時限の種類
+type PeriodKind int
+
+const (
+	// 曜日と時限があるもの（月1 など）
+	PeriodKindRegular PeriodKind = iota
+	// 集中
+	PeriodKindIntensive
+	// 応談
+	PeriodKindByAppointment
+	// 随時
+	PeriodKindAnytime
+	// NT
+	PeriodKindNT
+)
+
+// KdB の CSV での表記
+var periodKindLabels = []string{"", "集中", "応談", "随時", "NT"}
+
+// データベースの period_kind 型の値
+var periodKindNames = []string{"regular", "intensive", "by_appointment", "anytime", "nt"}
+
+func (k PeriodKind) String() string {
+	if k < PeriodKindRegular || int(k) >= len(periodKindNames) {
+		return fmt.Sprintf("PeriodKind(%d)", int(k))
+	}
+	return periodKindNames[k]
+}
+
+// 1 コマ分の曜時限
+// Kind が PeriodKindRegular でなければ Day は DayNone で，Slot は 0（「集中1」のように時限があればその値）
+type Period struct {
+	Day  Day
+	Slot int
+	Kind PeriodKind
+}
+
+// KdB の CSV での表記（月1, 集中 など）に戻す
+func (p Period) String() string {
+	slot := ""
+	if p.Slot > 0 {
+		slot = strconv.Itoa(p.Slot)
+	}
+	if p.Kind != PeriodKindRegular {
+		return p.Kind.label() + slot
+	}
+	return p.Day.String() + slot
+}
+
+func (k PeriodKind) label() string {
+	if k < PeriodKindRegular || int(k) >= len(periodKindLabels) {
+		return k.String()
+	}
+	return periodKindLabels[k]
+}
+
+// PeriodParser が返す 1 コマ分の文字列（月1, 集中 など）を Period にする
+func ParsePeriod(s string) (Period, error) {
+	p := Period{}
+	rest := ""
+	for k := PeriodKindIntensive; int(k) < len(periodKindLabels); k++ {
+		if strings.HasPrefix(s, periodKindLabels[k]) {
+			p.Kind = k
+			rest = strings.TrimPrefix(s, periodKindLabels[k])
+			break
+		}
+	}
+	if p.Kind == PeriodKindRegular {
+		for d := DayMonday; d <= DaySunday; d++ {
+			if strings.HasPrefix(s, dayLabels[d]) {
+				p.Day = d
+				rest = strings.TrimPrefix(s, dayLabels[d])
+				break
+			}
+		}
+		if p.Day == DayNone {
+			return Period{}, fmt.Errorf("unexpected period: %q", s)
+		}
+	}
+
+	if rest == "" {
+		if p.Kind == PeriodKindRegular {
+			return Period{}, fmt.Errorf("period without slot: %q", s)
+		}
+		return p, nil
+	}
+	slot, err := strconv.Atoi(rest)
+	if err != nil || slot < 1 {
+		return Period{}, fmt.Errorf("unexpected period slot: %q", s)
+	}
+	p.Slot = slot
+	return p, nil
+}
+
+// 曜時限をパースして Period にする
+// PeriodParser と同じ順番で返す
+func PeriodsParser(periodString string) ([]Period, error) {
+	strs, err := PeriodParser(periodString)
+	if err != nil {
+		return nil, err
+	}
+	periods := make([]Period, 0, len(strs))
+	for _, s := range strs {
+		p, err := ParsePeriod(s)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, p)
+	}
+	return periods, nil
+}
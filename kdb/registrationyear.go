@@ -0,0 +1,156 @@
+package kdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 標準履修年次の最大
+const MaxStandardRegistrationYear = 6
+
+// 標準履修年次
+// 1 から MaxStandardRegistrationYear までの年次の集合か，不明（KdB で "?"）
+type StandardRegistrationYears struct {
+	// n 年次なら n-1 ビット目が立つ
+	years   uint8
+	unknown bool
+}
+
+// 不明（"?"）か
+func (s StandardRegistrationYears) Unknown() bool {
+	return s.unknown
+}
+
+// year 年次を含むか
+func (s StandardRegistrationYears) Contains(year int) bool {
+	return year >= 1 && year <= MaxStandardRegistrationYear && s.years&(1<<uint(year-1)) != 0
+}
+
+// 含む年次を小さい順に返す
+func (s StandardRegistrationYears) Years() []int {
+	years := []int{}
+	for year := 1; year <= MaxStandardRegistrationYear; year++ {
+		if s.Contains(year) {
+			years = append(years, year)
+		}
+	}
+	return years
+}
+
+// データベースの standard_registration_year[] に保存する値
+// 不明なら {"?"}
+func (s StandardRegistrationYears) Strings() []string {
+	if s.unknown {
+		return []string{"?"}
+	}
+	strs := []string{}
+	for _, year := range s.Years() {
+		strs = append(strs, strconv.Itoa(year))
+	}
+	return strs
+}
+
+func (s *StandardRegistrationYears) add(from, to int) {
+	for year := from; year <= to; year++ {
+		s.years |= 1 << uint(year-1)
+	}
+}
+
+// 年次を範囲にする記号
+// 中黒も範囲とみなす（1・3 は 1, 2, 3 年次）
+func isYearRangeSeparator(r rune) bool {
+	switch r {
+	case '・', '-', '~', '〜', 'ー', '−', '–', '—':
+		return true
+	}
+	return false
+}
+
+// 範囲を並べる記号（1,3 は 1, 3 年次）
+func isYearListSeparator(r rune) bool {
+	switch r {
+	case ',', '、', '/':
+		return true
+	}
+	return false
+}
+
+const yearsOrMore = "以上"
+
+// 標準履修年次をパースする
+//
+//	years := "" | "?" | item (("," | "、" | "/") item)*
+//	item  := year (("・" | "-" | "~" | ...) year)* | year "以上"
+//
+// 全角の数字や記号は半角とみなし，空白は読み飛ばす
+// 年次が範囲外や順番が逆のもの，読めない文字があればエラーを返す
+func StandardRegistrationYearParser(yearString string) (StandardRegistrationYears, error) {
+	s := []rune(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, norm.NFKC.String(yearString)))
+	fail := func(format string, args ...interface{}) (StandardRegistrationYears, error) {
+		return StandardRegistrationYears{}, fmt.Errorf("standard registration year %q: "+format, append([]interface{}{yearString}, args...)...)
+	}
+
+	if len(s) == 0 {
+		return StandardRegistrationYears{}, nil
+	}
+	if string(s) == "?" {
+		return StandardRegistrationYears{unknown: true}, nil
+	}
+
+	years := StandardRegistrationYears{}
+	i := 0
+	// 年次を 1 つ読む
+	readYear := func() (int, bool) {
+		start := i
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if start == i || i-start > 1 {
+			return 0, false
+		}
+		year := int(s[start] - '0')
+		return year, year >= 1 && year <= MaxStandardRegistrationYear
+	}
+
+	for {
+		from, ok := readYear()
+		if !ok {
+			return fail("expected a year from 1 to %d at %d", MaxStandardRegistrationYear, i)
+		}
+		to := from
+		if strings.HasPrefix(string(s[i:]), yearsOrMore) {
+			i += len([]rune(yearsOrMore))
+			to = MaxStandardRegistrationYear
+		} else {
+			for i < len(s) && isYearRangeSeparator(s[i]) {
+				i++
+				next, ok := readYear()
+				if !ok {
+					return fail("expected a year from 1 to %d at %d", MaxStandardRegistrationYear, i)
+				}
+				if next < to {
+					return fail("years are not in order")
+				}
+				to = next
+			}
+		}
+		years.add(from, to)
+
+		if i == len(s) {
+			return years, nil
+		}
+		if !isYearListSeparator(s[i]) {
+			return fail("unexpected %q", string(s[i]))
+		}
+		i++
+	}
+}
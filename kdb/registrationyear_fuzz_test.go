@@ -0,0 +1,55 @@
+//go:build go1.18
+// +build go1.18
+
+package kdb
+
+import (
+	"strings"
+	"testing"
+)
+
+// KdB の CSV に実際に現れる標準履修年次
+var standardRegistrationYearSeeds = []string{
+	"",
+	"?",
+	"1",
+	"2",
+	"1・2",
+	"2・3",
+	"3・4",
+	"1 - 4",
+	"1-4",
+	"2 - 6",
+	"１・２",
+	"2以上",
+	"1,3",
+}
+
+func FuzzStandardRegistrationYearParser(f *testing.F) {
+	for _, seed := range standardRegistrationYearSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, yearString string) {
+		years, err := StandardRegistrationYearParser(yearString)
+		if err != nil {
+			return
+		}
+		if years.Unknown() {
+			return
+		}
+		for _, year := range years.Years() {
+			if year < 1 || year > MaxStandardRegistrationYear {
+				t.Fatalf("year %d out of range for %q", year, yearString)
+			}
+		}
+		// パースした結果を並べ直したものは同じ集合になる
+		s := strings.Join(years.Strings(), ",")
+		again, err := StandardRegistrationYearParser(s)
+		if err != nil {
+			t.Fatalf("%q parsed from %q: %v", s, yearString, err)
+		}
+		if again != years {
+			t.Fatalf("%q parsed from %q: got %v, want %v", s, yearString, again.Years(), years.Years())
+		}
+	})
+}
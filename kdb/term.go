@@ -0,0 +1,144 @@
+package kdb
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 実施学期の文字列に現れる語
+// 長いものから順に照合する
+var termWords = []struct {
+	word string
+	term string
+}{
+	{"夏季休業中", "夏季休業中"},
+	{"秋季休業中", "秋季休業中"},
+	{"春季休業中", "春季休業中"},
+	{"春学期", "春学期"},
+	{"秋学期", "秋学期"},
+	{"通年", "通年"},
+	{"随時", "随時"},
+}
+
+// 「春」「秋」の後ろに続くモジュール（春ABC など）
+var termModules = map[rune]map[rune]string{
+	'春': {'A': "春A", 'B': "春B", 'C': "春C"},
+	'秋': {'A': "秋A", 'B': "秋B", 'C': "秋C"},
+}
+
+// 実施学期の季節
+const (
+	TermSeasonSpring         = "spring"
+	TermSeasonFall           = "fall"
+	TermSeasonSummerVacation = "summer_vacation"
+	TermSeasonSpringVacation = "spring_vacation"
+	TermSeasonFallVacation   = "fall_vacation"
+	TermSeasonAllYear        = "all_year"
+	TermSeasonAnytime        = "anytime"
+)
+
+// 実施学期
+// データベースの terms テーブルの 1 行に当たる
+type Term struct {
+	Code    int
+	Label   string
+	LabelEn string
+	Season  string
+}
+
+// 実施学期の一覧
+// この順番が TermParser が返す順番で，terms.sort_order になる
+var Terms = []Term{
+	{TermSpringACode, "春A", "Spring A", TermSeasonSpring},
+	{TermSpringBCode, "春B", "Spring B", TermSeasonSpring},
+	{TermSpringCCode, "春C", "Spring C", TermSeasonSpring},
+	{TermFallACode, "秋A", "Fall A", TermSeasonFall},
+	{TermFallBCode, "秋B", "Fall B", TermSeasonFall},
+	{TermFallCCode, "秋C", "Fall C", TermSeasonFall},
+	{TermSummerVacationCode, "夏季休業中", "Summer Vacation", TermSeasonSummerVacation},
+	{TermFallVacationCode, "秋季休業中", "Fall Vacation", TermSeasonFallVacation},
+	{TermSpringVacationCode, "春季休業中", "Spring Vacation", TermSeasonSpringVacation},
+	{TermAllCode, "通年", "Full Year", TermSeasonAllYear},
+	{TermAnytimeCode, "随時", "Anytime", TermSeasonAnytime},
+	{TermSpringCode, "春学期", "Spring Semester", TermSeasonSpring},
+	{TermFallCode, "秋学期", "Fall Semester", TermSeasonFall},
+}
+
+// TermParser が返す順番
+var termNames = []string{}
+
+// termNames の何番目か
+var termOrder = map[string]int{}
+
+func init() {
+	for i, term := range Terms {
+		termNames = append(termNames, term.Label)
+		termOrder[term.Label] = i
+	}
+}
+
+// 語の区切りとして読み飛ばす文字
+func isTermSeparator(r rune) bool {
+	switch r {
+	case ' ', '\t', '\r', '\n', '・', ',', '、', '/':
+		return true
+	}
+	return false
+}
+
+// 開講時期をパースする
+// 「春ABC 秋A」のようなモジュールの組み合わせ，休業中，通年，随時，学期を読み，
+// 重複を除いて春A, 春B, ..., 秋学期 の順に並べて返す
+// 全角の英数字や空白は半角とみなし，知らない語があればエラーを返す
+func TermParser(termString string) ([]string, error) {
+	s := norm.NFKC.String(termString)
+
+	found := make([]bool, len(termOrder))
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if isTermSeparator(r) {
+			s = s[size:]
+			continue
+		}
+
+		if n, term := matchTermWord(s); n > 0 {
+			found[termOrder[term]] = true
+			s = s[n:]
+			continue
+		}
+
+		// 春・秋の後ろに A, B, C が 1 つ以上続く
+		if modules, ok := termModules[r]; ok && len(s) > size && modules[rune(s[size])] != "" {
+			s = s[size:]
+			for len(s) > 0 && modules[rune(s[0])] != "" {
+				found[termOrder[modules[rune(s[0])]]] = true
+				s = s[1:]
+			}
+			continue
+		}
+
+		return nil, fmt.Errorf("unknown term token: %q in %q", s, termString)
+	}
+
+	terms := []string{}
+	for i, term := range termNames {
+		if found[i] {
+			terms = append(terms, term)
+		}
+	}
+	return terms, nil
+}
+
+// s の先頭にある termWords の語を探す
+// n は読んだバイト数（見つからなければ 0）
+func matchTermWord(s string) (n int, term string) {
+	for _, w := range termWords {
+		if strings.HasPrefix(s, w.word) {
+			return len(w.word), w.term
+		}
+	}
+	return 0, ""
+}
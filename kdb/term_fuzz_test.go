@@ -0,0 +1,32 @@
+//go:build go1.18
+// +build go1.18
+
+package kdb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func FuzzTermParser(f *testing.F) {
+	for _, s := range []string{"", "春AB", "春ABC 秋A", "春C秋AB", "秋ＡＢ　春Ｃ", "春C 夏季休業中", "春学期・秋学期,通年", "春A 随時", "秋季休業中", "春A 冬学期", "春", "春D"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		terms, err := TermParser(s)
+		if err != nil {
+			return
+		}
+		for _, term := range terms {
+			if _, err := TermStrToInt(term); err != nil {
+				t.Fatalf("TermParser(%q) returned unknown term %q", s, term)
+			}
+		}
+		// 返したものを並べ直して読むと同じものになる
+		again, err := TermParser(strings.Join(terms, " "))
+		if err != nil || !reflect.DeepEqual(again, terms) {
+			t.Fatalf("TermParser(%q) = %v, but parsing it again = %v, %v", s, terms, again, err)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+// 時限と時刻の対応（時間割）を扱うパッケージ
+//
+// 時間割は年度によって変わることがあるため，適用を始める年度ごとに YAML で書いておく．
+//
+//	timetables:
+//	  - from: 2021
+//	    slots:
+//	      1: {start: "8:40", end: "9:55"}
+//	      2: {start: "10:10", end: "11:25"}
+package timetable
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sylms/csv2sql/kdb"
+	"gopkg.in/yaml.v2"
+)
+
+// 時刻（時・分）
+type Clock struct {
+	Hour   int
+	Minute int
+}
+
+// "8:40" のような文字列から Clock を作る
+func ParseClock(s string) (Clock, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return Clock{}, fmt.Errorf("invalid clock: %q", s)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return Clock{}, fmt.Errorf("invalid clock: %q", s)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 || len(parts[1]) != 2 {
+		return Clock{}, fmt.Errorf("invalid clock: %q", s)
+	}
+	return Clock{Hour: hour, Minute: minute}, nil
+}
+
+// 0 時からの分
+func (c Clock) Minutes() int {
+	return c.Hour*60 + c.Minute
+}
+
+// "08:40" の形で返す（PostgreSQL の time にそのまま渡せる）
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour, c.Minute)
+}
+
+func (c *Clock) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := ParseClock(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
+
+// 1 時限の開始・終了時刻
+type SlotTime struct {
+	Start Clock `yaml:"start"`
+	End   Clock `yaml:"end"`
+}
+
+// ある年度から使う時間割
+type Timetable struct {
+	// この年度から次の時間割の年度の前まで使う
+	From  int              `yaml:"from"`
+	Slots map[int]SlotTime `yaml:"slots"`
+}
+
+// 時間割の一覧
+type Config struct {
+	Timetables []Timetable `yaml:"timetables"`
+}
+
+// YAML を読む
+func Load(r io.Reader) (*Config, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(b)
+}
+
+func Parse(b []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, err
+	}
+	seen := map[int]bool{}
+	for _, t := range c.Timetables {
+		if seen[t.From] {
+			return nil, fmt.Errorf("duplicated timetable from %d", t.From)
+		}
+		seen[t.From] = true
+		for slot, st := range t.Slots {
+			if slot < 1 {
+				return nil, fmt.Errorf("timetable from %d: invalid slot %d", t.From, slot)
+			}
+			if st.End.Minutes() <= st.Start.Minutes() {
+				return nil, fmt.Errorf("timetable from %d: slot %d ends before it starts", t.From, slot)
+			}
+		}
+	}
+	sort.Slice(c.Timetables, func(i, j int) bool {
+		return c.Timetables[i].From < c.Timetables[j].From
+	})
+	return c, nil
+}
+
+// year の時間割を返す
+// year 以前から使っているもののうち最も新しいもの
+func (c *Config) ForYear(year int) (Timetable, bool) {
+	for i := len(c.Timetables) - 1; i >= 0; i-- {
+		if c.Timetables[i].From <= year {
+			return c.Timetables[i], true
+		}
+	}
+	return Timetable{}, false
+}
+
+// 曜時限の開始・終了時刻を返す
+// 集中などの時限がないものや，時間割にない時限は ok が false
+func (t Timetable) Times(p kdb.Period) (st SlotTime, ok bool) {
+	if p.Kind != kdb.PeriodKindRegular {
+		return SlotTime{}, false
+	}
+	st, ok = t.Slots[p.Slot]
+	return st, ok
+}
@@ -0,0 +1,142 @@
+package timetable
+
+import (
+	"testing"
+
+	"github.com/sylms/csv2sql/kdb"
+)
+
+const testConfig = `
+timetables:
+  - from: 2021
+    slots:
+      1: {start: "8:40", end: "9:55"}
+      2: {start: "10:10", end: "11:25"}
+  - from: 2019
+    slots:
+      1: {start: "8:40", end: "10:10"}
+      2: {start: "10:20", end: "11:50"}
+`
+
+func Test_Parse(t *testing.T) {
+	type args struct {
+		yaml string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			args: args{yaml: testConfig},
+		},
+		{
+			name:    "invalid clock",
+			args:    args{yaml: "timetables:\n  - from: 2021\n    slots:\n      1: {start: \"8:4\", end: \"9:55\"}\n"},
+			wantErr: true,
+		},
+		{
+			name:    "ends before it starts",
+			args:    args{yaml: "timetables:\n  - from: 2021\n    slots:\n      1: {start: \"9:55\", end: \"8:40\"}\n"},
+			wantErr: true,
+		},
+		{
+			name:    "slot 0",
+			args:    args{yaml: "timetables:\n  - from: 2021\n    slots:\n      0: {start: \"8:40\", end: \"9:55\"}\n"},
+			wantErr: true,
+		},
+		{
+			name:    "duplicated from",
+			args:    args{yaml: "timetables:\n  - from: 2021\n  - from: 2021\n"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			args:    args{yaml: "timetables:\n  - from: 2021\n    slot: {}\n"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.args.yaml))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Timetable_Times(t *testing.T) {
+	config, err := Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := config.ForYear(2018); ok {
+		t.Error("ForYear(2018) found a timetable")
+	}
+
+	type args struct {
+		year   int
+		period kdb.Period
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantStart string
+		wantEnd   string
+		wantOk    bool
+	}{
+		{
+			name:      "first slot",
+			args:      args{year: 2022, period: kdb.Period{Day: kdb.DayMonday, Slot: 1}},
+			wantStart: "08:40",
+			wantEnd:   "09:55",
+			wantOk:    true,
+		},
+		{
+			name:      "first year of the newer timetable",
+			args:      args{year: 2021, period: kdb.Period{Day: kdb.DayFriday, Slot: 2}},
+			wantStart: "10:10",
+			wantEnd:   "11:25",
+			wantOk:    true,
+		},
+		{
+			name:      "last year of the older timetable",
+			args:      args{year: 2020, period: kdb.Period{Day: kdb.DayFriday, Slot: 2}},
+			wantStart: "10:20",
+			wantEnd:   "11:50",
+			wantOk:    true,
+		},
+		{
+			name:      "first year of the older timetable",
+			args:      args{year: 2019, period: kdb.Period{Day: kdb.DayMonday, Slot: 1}},
+			wantStart: "08:40",
+			wantEnd:   "10:10",
+			wantOk:    true,
+		},
+		{
+			name: "slot not in timetable",
+			args: args{year: 2022, period: kdb.Period{Day: kdb.DayMonday, Slot: 9}},
+		},
+		{
+			name: "intensive",
+			args: args{year: 2022, period: kdb.Period{Slot: 1, Kind: kdb.PeriodKindIntensive}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt2, ok := config.ForYear(tt.args.year)
+			if !ok {
+				t.Fatalf("ForYear(%d) found no timetable", tt.args.year)
+			}
+			got, ok := tt2.Times(tt.args.period)
+			if ok != tt.wantOk {
+				t.Fatalf("Times() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && (got.Start.String() != tt.wantStart || got.End.String() != tt.wantEnd) {
+				t.Errorf("Times() = %v-%v, want %v-%v", got.Start, got.End, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
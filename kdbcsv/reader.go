@@ -0,0 +1,326 @@
+// KdB からエクスポートした CSV を読むためのパッケージ
+//
+// KdB の CSV は全てのフィールドがダブルクォーテーションで囲まれているが，
+// フィールド中のダブルクォーテーションはエスケープされていない．
+// そのため encoding/csv では正しく読めないので，
+// 「ダブルクォーテーションの直後がカンマか改行であればフィールドの終わり」とみなして読む．
+// フィールドの数が分かっている場合はそれも手がかりにし，判断がつかない箇所は行と列を報告する．
+// どの「","」がフィールドの区切りか決められないレコードはエラーにする．
+package kdbcsv
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// レコードのフィールド数がヘッダーと異なる
+	ErrFieldCount = errors.New("wrong number of fields")
+	// 閉じるダブルクォーテーションがないままファイルが終わった
+	ErrQuote = errors.New("extraneous or missing \" in quoted-field")
+	// ダブルクォーテーションの直後がカンマだが，フィールドの区切りとはみなさなかった
+	ErrAmbiguousComma = errors.New("quote followed by comma is treated as a part of the field")
+	// ダブルクォーテーションで囲まれたカンマがフィールドの区切りより多く，どれが区切りか決められない
+	ErrAmbiguousFields = errors.New("more quoted commas than field separators")
+	// ダブルクォーテーションの直後が改行だが，レコードの終わりとはみなさなかった
+	ErrAmbiguousNewline = errors.New("quote followed by newline is treated as a part of the field")
+)
+
+// 読み込み中に見つかった問題の位置
+// Line, Column は 1 始まりで，Column は文字（rune）単位
+type ParseError struct {
+	StartLine int
+	Line      int
+	Column    int
+	Err       error
+}
+
+func (e *ParseError) Error() string {
+	if e.StartLine != e.Line {
+		return fmt.Sprintf("record on line %d; parse error on line %d, column %d: %v", e.StartLine, e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("parse error on line %d, column %d: %v", e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// KdB の CSV を 1 レコードずつ読む
+// gocsv.CSVReader を満たしている
+type Reader struct {
+	// 1 レコードあたりのフィールド数
+	// 0 の場合は最初に読んだレコード（ヘッダー）のフィールド数を使う
+	FieldsPerRecord int
+
+	// 区切りかどうか判断がつかない箇所を見つけたときに呼ばれる
+	// nil の場合は何もしない
+	OnAmbiguity func(*ParseError)
+
+	r *bufio.Reader
+
+	// 次に読む文字の位置
+	line   int
+	column int
+
+	// 最後に読んだレコードが始まった行
+	recordLine int
+	// 最後に読んだレコードで，ダブルクォーテーションの直後のカンマを区切りとみなした数
+	quotedCommas int
+	// 直前に読んだ文字が \r か（\r\n を 1 行と数えるため）
+	afterCR bool
+
+	field bytes.Buffer
+}
+
+// r は UTF-8 にデコード済みのものを渡す
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		r:      bufio.NewReader(r),
+		line:   1,
+		column: 1,
+	}
+}
+
+// 最後に読んだレコードが始まった行を返す
+func (r *Reader) Line() int {
+	return r.recordLine
+}
+
+// 1 レコードを読む
+// ファイルの終わりでは io.EOF を返す
+// フィールド数が異なる場合は ErrFieldCount を持つ *ParseError とともにレコードを返す
+func (r *Reader) Read() ([]string, error) {
+	// 空行は読み飛ばす
+	for {
+		b, err := r.r.Peek(1)
+		if len(b) == 0 {
+			if err == nil || err == io.EOF {
+				return nil, io.EOF
+			}
+			return nil, err
+		}
+		if b[0] != '\r' && b[0] != '\n' {
+			break
+		}
+		r.skipRune()
+	}
+
+	r.recordLine = r.line
+	r.quotedCommas = 0
+	record := []string{}
+	for {
+		field, end, err := r.readField(len(record))
+		record = append(record, field)
+		if err != nil {
+			return record, err
+		}
+		if end {
+			break
+		}
+	}
+
+	if r.FieldsPerRecord == 0 {
+		r.FieldsPerRecord = len(record)
+	} else if len(record) != r.FieldsPerRecord {
+		// 多すぎる分だけダブルクォーテーションの直後のカンマで区切っていれば，
+		// そのうちどれがフィールド中のカンマなのか決められない
+		if extra := len(record) - r.FieldsPerRecord; extra > 0 && r.quotedCommas >= extra {
+			return record, r.errorAt(r.recordLine, 1, ErrAmbiguousFields)
+		}
+		return record, r.errorAt(r.recordLine, 1, ErrFieldCount)
+	}
+	return record, nil
+}
+
+// 残りのレコードを全て読む
+func (r *Reader) ReadAll() ([][]string, error) {
+	records := [][]string{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// index 番目のフィールドを読む
+// end はそのフィールドでレコードが終わったかどうか
+func (r *Reader) readField(index int) (field string, end bool, err error) {
+	r.field.Reset()
+
+	// 行頭やカンマの後ろにある空白は，ダブルクォーテーションが続くなら読み飛ばす
+	if n, next, ok := r.peekAfterSpaces(0); ok && next == '"' {
+		r.discard(n)
+	}
+
+	b, _ := r.r.Peek(1)
+	if len(b) == 0 {
+		return "", true, nil
+	}
+	if b[0] != '"' {
+		return r.readUnquotedField()
+	}
+
+	startLine, startColumn := r.line, r.column
+	r.skipRune()
+	for {
+		c, err := r.readRune()
+		if err == io.EOF {
+			return r.field.String(), true, r.errorAt(startLine, startColumn, ErrQuote)
+		}
+		if err != nil {
+			return "", true, err
+		}
+		if c != '"' {
+			r.field.WriteRune(c)
+			continue
+		}
+
+		quoteLine, quoteColumn := r.line, r.column-1
+		n, next, ok := r.peekAfterSpaces(0)
+		switch {
+		case !ok:
+			// ファイルの終わり
+			r.discard(n)
+			return r.field.String(), true, nil
+
+		case next == ',':
+			// KdB の CSV は全てのフィールドがダブルクォーテーションで囲まれているため，
+			// カンマの後ろがダブルクォーテーションでなければフィールドの一部とみなす
+			_, after, ok := r.peekAfterSpaces(n + 1)
+			if ok && after != '"' && after != ',' && after != '\r' && after != '\n' {
+				r.ambiguity(quoteLine, quoteColumn, ErrAmbiguousComma)
+				r.field.WriteRune(c)
+				continue
+			}
+			// 最後のフィールドでも区切りとみなし，フィールドが多すぎれば Read でエラーにする
+			r.discard(n + 1)
+			r.quotedCommas++
+			return r.field.String(), false, nil
+
+		case next == '\r' || next == '\n':
+			// フィールドが足りないうちに改行が来た場合，次の行がダブルクォーテーションで
+			// 始まっていなければフィールド中の改行とみなす
+			if r.FieldsPerRecord > 0 && index < r.FieldsPerRecord-1 && !r.nextLineStartsRecord(n) {
+				r.ambiguity(quoteLine, quoteColumn, ErrAmbiguousNewline)
+				r.field.WriteRune(c)
+				continue
+			}
+			r.discard(n)
+			r.readNewline()
+			return r.field.String(), true, nil
+
+		default:
+			// エスケープされていないダブルクォーテーション
+			r.field.WriteRune(c)
+		}
+	}
+}
+
+// ダブルクォーテーションで囲まれていないフィールドを読む
+func (r *Reader) readUnquotedField() (string, bool, error) {
+	for {
+		b, _ := r.r.Peek(1)
+		if len(b) == 0 {
+			return r.field.String(), true, nil
+		}
+		switch b[0] {
+		case ',':
+			r.skipRune()
+			return r.field.String(), false, nil
+		case '\r', '\n':
+			r.readNewline()
+			return r.field.String(), true, nil
+		}
+		c, err := r.readRune()
+		if err != nil {
+			return "", true, err
+		}
+		r.field.WriteRune(c)
+	}
+}
+
+// offset バイト目にある改行の次の行が，新しいレコードの始まりに見えるかどうか
+func (r *Reader) nextLineStartsRecord(offset int) bool {
+	b, _ := r.r.Peek(offset + 2)
+	if len(b) > offset+1 && b[offset] == '\r' && b[offset+1] == '\n' {
+		offset += 2
+	} else {
+		offset++
+	}
+	_, next, ok := r.peekAfterSpaces(offset)
+	return !ok || next == '"'
+}
+
+// offset バイト目から空白（スペース，タブ）を読み飛ばした次の文字を先読みする
+// n は offset から次の文字までのバイト数，ok はファイルの終わりでないかどうか
+func (r *Reader) peekAfterSpaces(offset int) (n int, next byte, ok bool) {
+	for i := offset; ; i++ {
+		b, _ := r.r.Peek(i + 1)
+		if len(b) <= i {
+			return i - offset, 0, false
+		}
+		if b[i] != ' ' && b[i] != '\t' {
+			return i - offset, b[i], true
+		}
+	}
+}
+
+func (r *Reader) readRune() (rune, error) {
+	c, _, err := r.r.ReadRune()
+	if err != nil {
+		return c, err
+	}
+	switch {
+	case c == '\n' && r.afterCR:
+		// \r\n の \r で行を進めている
+	case c == '\r' || c == '\n':
+		r.line++
+		r.column = 1
+	default:
+		r.column++
+	}
+	r.afterCR = c == '\r'
+	return c, nil
+}
+
+// Peek で存在を確認した 1 文字を読み飛ばす
+func (r *Reader) skipRune() {
+	_, _ = r.readRune()
+}
+
+// 1 バイト文字を n 個読み飛ばす
+func (r *Reader) discard(n int) {
+	for i := 0; i < n; i++ {
+		r.skipRune()
+	}
+}
+
+// \r\n, \n, \r のいずれかを 1 つ読む
+func (r *Reader) readNewline() {
+	c, err := r.readRune()
+	if err != nil || c != '\r' {
+		return
+	}
+	if b, _ := r.r.Peek(1); len(b) > 0 && b[0] == '\n' {
+		r.skipRune()
+	}
+}
+
+func (r *Reader) ambiguity(line, column int, err error) {
+	if r.OnAmbiguity != nil {
+		r.OnAmbiguity(r.errorAt(line, column, err))
+	}
+}
+
+func (r *Reader) errorAt(line, column int, err error) *ParseError {
+	return &ParseError{StartLine: r.recordLine, Line: line, Column: column, Err: err}
+}
@@ -0,0 +1,114 @@
+package kdbcsv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testdata/*.csv を読んだ結果を testdata/*.json と比較する
+// json にはレコード，曖昧だった箇所（"行:列"），エラーメッセージを書いておく
+func Test_Reader_corpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no test corpus")
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".csv")
+		t.Run(name, func(t *testing.T) {
+			want := struct {
+				Records     [][]string `json:"records"`
+				Ambiguities []string   `json:"ambiguities"`
+				Error       string     `json:"error"`
+			}{}
+			b, err := ioutil.ReadFile(strings.TrimSuffix(path, ".csv") + ".json")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(b, &want); err != nil {
+				t.Fatal(err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			ambiguities := []string{}
+			r := NewReader(f)
+			r.OnAmbiguity = func(e *ParseError) {
+				ambiguities = append(ambiguities, fmt.Sprintf("%d:%d", e.Line, e.Column))
+			}
+
+			records := [][]string{}
+			gotErr := ""
+			for {
+				record, err := r.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					gotErr = err.Error()
+					break
+				}
+				records = append(records, record)
+			}
+
+			if gotErr != want.Error {
+				t.Errorf("Read() error = %q, want %q", gotErr, want.Error)
+			}
+			if !reflect.DeepEqual(records, want.Records) {
+				t.Errorf("Read() = %q, want %q", records, want.Records)
+			}
+			if !reflect.DeepEqual(ambiguities, want.Ambiguities) {
+				t.Errorf("ambiguities = %v, want %v", ambiguities, want.Ambiguities)
+			}
+		})
+	}
+}
+
+func Test_Reader_Line(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []int
+	}{
+		{
+			name:  "CRLF",
+			input: "\"a\",\"b\"\r\n\"1\",\"複数\r\n行\"\r\n\"2\",\"x\"\r\n",
+			want:  []int{1, 2, 4},
+		},
+		{
+			name:  "CR だけの改行",
+			input: "\"a\",\"b\"\r\"1\",\"複数\r行\"\r\"2\",\"x\"\r",
+			want:  []int{1, 2, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewReader(strings.NewReader(tt.input))
+			for _, line := range tt.want {
+				if _, err := r.Read(); err != nil {
+					t.Fatal(err)
+				}
+				if r.Line() != line {
+					t.Errorf("Line() = %d, want %d", r.Line(), line)
+				}
+			}
+			if _, err := r.Read(); err != io.EOF {
+				t.Errorf("Read() error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
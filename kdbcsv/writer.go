@@ -0,0 +1,31 @@
+package kdbcsv
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// KdB と同じ形式で CSV を書く
+// 全てのフィールドをダブルクォーテーションで囲み，フィールド中のダブルクォーテーションはエスケープしない．
+// 書いたものは Reader でそのまま読み直せる
+type Writer struct {
+	w *bufio.Writer
+}
+
+// w には UTF-8 のまま書くので，Shift_JIS にする場合はエンコーダーを挟んで渡す
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// 1 レコードを書く
+// KdB の CSV に合わせて改行は \r\n にする
+func (w *Writer) Write(record []string) error {
+	_, err := w.w.WriteString(`"` + strings.Join(record, `","`) + "\"\r\n")
+	return err
+}
+
+// バッファに残っているものを書き出す
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
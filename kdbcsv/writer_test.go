@@ -0,0 +1,35 @@
+package kdbcsv
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// 書いたものを Reader で読み直すと元に戻る
+func Test_Writer_roundTrip(t *testing.T) {
+	records := [][]string{
+		{"科目番号", "科目名", "備考"},
+		{"GB10234", `"情報"科学特論`, "複数\r\n行"},
+		{"GB10244", "", `"引用", のあとに文字`},
+	}
+
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf)
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, records) {
+		t.Errorf("ReadAll() = %q, want %q", got, records)
+	}
+}
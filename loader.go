@@ -0,0 +1,240 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// 取り込む科目を一旦流し込む一時テーブル
+// ここから courses に upsert する
+const stagingTable = "courses_staging"
+
+const (
+	loaderCopy   = "copy"
+	loaderInsert = "insert"
+)
+
+// 一時テーブルにだけあるカラム
+// courses には保存せず，course_periods などの関連テーブルを作るのに使う
+var stagingExtraColumns = []struct {
+	name string
+	typ  string
+}{
+	// Courses.Periods を曜日・時限・種類ごとの配列にしたもの（曜日や時限がないものは 0）
+	{"period_days", "int[]"},
+	{"period_slots", "int[]"},
+	{"period_kinds", "text[]"},
+	// Courses.ParsedCourseNumber の組織と水準（分けられなければ '' と 0）
+	{"course_prefix", "text"},
+	{"course_level", "int"},
+	// Courses.CreditsNumeric（"?" や空なら null）
+	{"credits_numeric", "numeric(4,1)"},
+}
+
+// 一時テーブルに流し込むカラム
+func stagingColumns() []string {
+	columns := append([]string{}, courseColumns...)
+	for _, c := range stagingExtraColumns {
+		columns = append(columns, c.name)
+	}
+	return columns
+}
+
+// 一時テーブルに科目を流し込む方法
+// courses が閉じられるまで読み続ける
+// 途中でエラーを返した場合，courses の残りは読まない
+type courseLoader interface {
+	load(tx *sqlx.Tx, courses <-chan Courses) error
+}
+
+func newCourseLoader(name string) (courseLoader, error) {
+	switch name {
+	case loaderCopy:
+		return copyLoader{}, nil
+	case loaderInsert:
+		return namedExecLoader{}, nil
+	default:
+		return nil, errors.Errorf("unknown loader: %s", name)
+	}
+}
+
+// courses と同じカラムを持つ一時テーブルを作る
+// seq は CSV に現れた順番で，同じ科目が複数あったときに後のものを使うためのもの
+func createStagingTable(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`create temp table ` + stagingTable + ` on commit drop as
+		select ` + joinColumns(courseColumns) + ` from courses with no data`)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	add := []string{"add column seq bigserial"}
+	for _, c := range stagingExtraColumns {
+		add = append(add, "add column "+c.name+" "+c.typ)
+	}
+	_, err = tx.Exec(`alter table ` + stagingTable + ` ` + strings.Join(add, ", "))
+	return errors.WithStack(err)
+}
+
+// COPY で 1 行ずつ流し込む
+type copyLoader struct{}
+
+func (copyLoader) load(tx *sqlx.Tx, courses <-chan Courses) error {
+	stmt, err := tx.Prepare(pq.CopyIn(stagingTable, stagingColumns()...))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	for c := range courses {
+		_, err := stmt.Exec(append(courseValues(c), stagingExtraValues(c)...)...)
+		if err != nil {
+			stmt.Close()
+			return errors.WithStack(err)
+		}
+	}
+
+	// 引数なしの Exec で COPY を終える
+	_, err = stmt.Exec()
+	if err != nil {
+		stmt.Close()
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(stmt.Close())
+}
+
+// courseColumns と同じ順番で値を並べる
+func courseValues(c Courses) []interface{} {
+	return []interface{}{
+		c.CourseNumber,
+		c.CourseName,
+		c.InstructionalType,
+		c.Credits,
+		pq.Array(c.StandardRegistrationYear),
+		pq.Array(c.Term),
+		pq.Array(c.Period),
+		c.Classroom,
+		pq.Array(c.Instructor),
+		c.CourseOverview,
+		c.Remarks,
+		c.CreditedAuditors,
+		c.ApplicationConditions,
+		c.AltCourseName,
+		c.CourseCode,
+		c.CourseCodeName,
+		c.CSVUpdatedAt,
+		c.Year,
+		c.CreatedAt,
+		c.UpdatedAt,
+	}
+}
+
+// stagingExtraColumns と同じ順番で値を並べる
+func stagingExtraValues(c Courses) []interface{} {
+	days := []int{}
+	slots := []int{}
+	kinds := []string{}
+	for _, p := range c.Periods {
+		days = append(days, int(p.Day))
+		slots = append(slots, p.Slot)
+		kinds = append(kinds, p.Kind.String())
+	}
+	return []interface{}{
+		pq.Array(days), pq.Array(slots), pq.Array(kinds),
+		c.ParsedCourseNumber.Prefix, c.ParsedCourseNumber.Level,
+		c.CreditsNumeric,
+	}
+}
+
+// NamedExec で複数行ずつ insert する
+// COPY が使えない環境のために残している
+type namedExecLoader struct{}
+
+func (namedExecLoader) load(tx *sqlx.Tx, courses <-chan Courses) error {
+	type insertPrepare struct {
+		CourseNumber             string      `db:"course_number"`
+		CourseName               string      `db:"course_name"`
+		InstructionalType        int         `db:"instructional_type"`
+		Credits                  string      `db:"credits"`
+		StandardRegistrationYear interface{} `db:"standard_registration_year"`
+		Term                     interface{} `db:"term"`
+		Period                   interface{} `db:"period_"`
+		Classroom                string      `db:"classroom"`
+		Instructor               interface{} `db:"instructor"`
+		CourseOverview           string      `db:"course_overview"`
+		Remarks                  string      `db:"remarks"`
+		CreditedAuditors         int         `db:"credited_auditors"`
+		ApplicationConditions    string      `db:"application_conditions"`
+		AltCourseName            string      `db:"alt_course_name"`
+		CourseCode               string      `db:"course_code"`
+		CourseCodeName           string      `db:"course_code_name"`
+		CSVUpdatedAt             time.Time   `db:"csv_updated_at"`
+		Year                     int         `db:"year"`
+		CreatedAt                time.Time   `db:"created_at"`
+		UpdatedAt                time.Time   `db:"updated_at"`
+		PeriodDays               interface{} `db:"period_days"`
+		PeriodSlots              interface{} `db:"period_slots"`
+		PeriodKinds              interface{} `db:"period_kinds"`
+		CoursePrefix             interface{} `db:"course_prefix"`
+		CourseLevel              interface{} `db:"course_level"`
+		CreditsNumeric           interface{} `db:"credits_numeric"`
+	}
+
+	// 全て（約 19,000 件）を一気に insert しようとしたら制限に引っかかった
+	// pq: got 395920 parameters but PostgreSQL only supports 65535 parameters
+	// 1 回の insert のパラメーターがこれに収まるよう，カラム数から区切るレコード数を決める
+	const maxParameters = 65535
+	bulkInsertLimit := maxParameters / len(stagingColumns())
+
+	query := `insert into ` + stagingTable + ` (` + joinColumns(stagingColumns()) + `) values (` + joinColumnsWithPrefix(stagingColumns(), ":") + `)`
+	batch := make([]insertPrepare, 0, bulkInsertLimit)
+	flush := func() error {
+		// 科目が 1 件もない場合、空の batch を NamedExec に渡すとエラーになるため飛ばす
+		if len(batch) == 0 {
+			return nil
+		}
+		_, err := tx.NamedExec(query, batch)
+		batch = batch[:0]
+		return errors.WithStack(err)
+	}
+
+	for c := range courses {
+		extra := stagingExtraValues(c)
+		batch = append(batch, insertPrepare{
+			CourseNumber:             c.CourseNumber,
+			CourseName:               c.CourseName,
+			InstructionalType:        int(c.InstructionalType),
+			Credits:                  c.Credits,
+			StandardRegistrationYear: pq.Array(c.StandardRegistrationYear),
+			Term:                     pq.Array(c.Term),
+			Period:                   pq.Array(c.Period),
+			Classroom:                c.Classroom,
+			Instructor:               pq.Array(c.Instructor),
+			CourseOverview:           c.CourseOverview,
+			Remarks:                  c.Remarks,
+			CreditedAuditors:         int(c.CreditedAuditors),
+			ApplicationConditions:    c.ApplicationConditions,
+			AltCourseName:            c.AltCourseName,
+			CourseCode:               c.CourseCode,
+			CourseCodeName:           c.CourseCodeName,
+			CSVUpdatedAt:             c.CSVUpdatedAt,
+			Year:                     c.Year,
+			CreatedAt:                c.CreatedAt,
+			UpdatedAt:                c.UpdatedAt,
+			PeriodDays:               extra[0],
+			PeriodSlots:              extra[1],
+			PeriodKinds:              extra[2],
+			CoursePrefix:             extra[3],
+			CourseLevel:              extra[4],
+			CreditsNumeric:           extra[5],
+		})
+		if len(batch) == bulkInsertLimit {
+			err := flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
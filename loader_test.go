@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	migrate "github.com/rubenv/sql-migrate"
+	"github.com/sylms/csv2sql/kdb"
+)
+
+func Test_courseValues(t *testing.T) {
+	got := len(courseValues(Courses{}))
+	if got != len(courseColumns) {
+		t.Errorf("len(courseValues()) = %d, want %d", got, len(courseColumns))
+	}
+	got = len(stagingExtraValues(Courses{}))
+	if got != len(stagingExtraColumns) {
+		t.Errorf("len(stagingExtraValues()) = %d, want %d", got, len(stagingExtraColumns))
+	}
+}
+
+// 約 20,000 件の KdB の CSV を想定した科目を作る
+func syntheticCourses(n int, year int) []Courses {
+	jst, _ := time.LoadLocation("Asia/Tokyo")
+	updatedAt := time.Date(2021, 3, 1, 14, 27, 49, 0, jst)
+	courses := make([]Courses, 0, n)
+	for i := 0; i < n; i++ {
+		courses = append(courses, Courses{
+			CourseNumber:             fmt.Sprintf("GB%05d", i),
+			CourseName:               fmt.Sprintf("科目 %d", i),
+			InstructionalType:        kdb.InstructionalType(i % 9),
+			Credits:                  "2.0",
+			StandardRegistrationYear: []string{"1", "2"},
+			Term:                     []int{1, 2},
+			Period:                   []string{"月1", "月2"},
+			Periods:                  []kdb.Period{{Day: kdb.DayMonday, Slot: 1}, {Day: kdb.DayMonday, Slot: 2}},
+			Classroom:                "3A204",
+			Instructor:               []string{"筑波 太郎", "筑波 花子"},
+			CourseOverview:           "授業の概要。\"引用\"や,カンマ,を含む",
+			Remarks:                  "備考",
+			CreditedAuditors:         kdb.CreditedAuditors(i % 4),
+			AltCourseName:            "Course",
+			CourseCode:               "0ALB101",
+			CourseCodeName:           "専門科目",
+			CSVUpdatedAt:             updatedAt,
+			Year:                     year,
+			CreatedAt:                updatedAt,
+			UpdatedAt:                updatedAt,
+		})
+	}
+	return courses
+}
+
+// courses を順に送るチャネルを返す
+func sendCourses(courses []Courses) <-chan Courses {
+	ch := make(chan Courses, pipelineBufferSize)
+	go func() {
+		defer close(ch)
+		for _, c := range courses {
+			ch <- c
+		}
+	}()
+	return ch
+}
+
+// SYLMS_POSTGRES_* が設定されている場合だけデータベースに接続する
+func openTestDB(tb testing.TB) *sqlx.DB {
+	opts := &rootOptions{
+		postgresDB:       os.Getenv(envSylmsPostgresDBKey),
+		postgresUser:     os.Getenv(envSylmsPostgresUserKey),
+		postgresPassword: os.Getenv(envSylmsPostgresPasswordKey),
+		postgresHost:     os.Getenv(envSylmsPostgresHostKey),
+		postgresPort:     os.Getenv(envSylmsPostgresPortKey),
+	}
+	if opts.postgresHost == "" {
+		tb.Skip("database is not configured")
+	}
+	db, err := opts.openDB()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := execMigrate(db, migrate.Up, 0); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+func benchmarkLoader(b *testing.B, name string) {
+	db := openTestDB(b)
+	defer db.Close()
+
+	loader, err := newCourseLoader(name)
+	if err != nil {
+		b.Fatal(err)
+	}
+	courses := syntheticCourses(20000, 2022)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tx, err := db.Beginx()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := createStagingTable(tx); err != nil {
+			b.Fatal(err)
+		}
+		if err := loader.load(tx, sendCourses(courses)); err != nil {
+			b.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoader_copy(b *testing.B) {
+	benchmarkLoader(b, loaderCopy)
+}
+
+func BenchmarkLoader_insert(b *testing.B) {
+	benchmarkLoader(b, loaderInsert)
+}
@@ -37,3 +37,8 @@ create table if not exists courses (
 	);
 
 -- +migrate Down
+
+drop table if exists courses;
+drop type if exists standard_registration_year;
+drop type if exists credited_auditors;
+drop type if exists instructional_type;
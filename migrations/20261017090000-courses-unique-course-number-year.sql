@@ -0,0 +1,15 @@
+-- +migrate Up
+
+-- 同じ年度の CSV を取り込み直すと科目が重複していたため，
+-- 最後に取り込んだもの以外を削除してから一意制約をつける
+delete from courses a
+  using courses b
+  where a.course_number = b.course_number
+    and a.year = b.year
+    and a.id < b.id;
+
+alter table courses add constraint courses_course_number_year_key unique (course_number, year);
+
+-- +migrate Down
+
+alter table courses drop constraint if exists courses_course_number_year_key;
@@ -0,0 +1,8 @@
+-- +migrate Up
+
+-- KdB から消えた科目を import --sync で論理削除したときの日時
+alter table courses add column deleted_at timestamp with time zone;
+
+-- +migrate Down
+
+alter table courses drop column if exists deleted_at;
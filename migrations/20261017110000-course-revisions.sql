@@ -0,0 +1,17 @@
+-- +migrate Up
+
+-- 取り込みのたびに科目の内容が変わったカラムを記録する
+create table if not exists course_revisions (
+  id serial not null,
+  course_id int not null references courses (id) on delete cascade,
+  changes jsonb not null, -- 変更のあったカラム {"カラム名": {"old": 変更前, "new": 変更後}}
+  csv_updated_at timestamp with time zone not null, -- 変更の元になった CSV の「データ更新日」
+  created_at timestamp with time zone not null, -- 記録した日時
+  primary key (id)
+);
+
+create index if not exists course_revisions_course_id_idx on course_revisions (course_id);
+
+-- +migrate Down
+
+drop table if exists course_revisions;
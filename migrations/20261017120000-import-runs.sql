@@ -0,0 +1,29 @@
+-- +migrate Up
+
+-- import を実行するたびに 1 行記録する
+create table if not exists import_runs (
+  id serial not null,
+  started_at timestamp with time zone not null, -- 開始日時
+  finished_at timestamp with time zone, -- 終了日時（実行中は null）
+  status varchar(16) not null, -- running, succeeded, failed
+  year int not null, -- 取り込んだ年度
+  input_paths text[] not null default '{}', -- 読み込んだ CSV
+  input_sha256 varchar(64)[] not null default '{}', -- 読み込んだ CSV の SHA-256（input_paths と同じ順番）
+  inserted int not null default 0, -- 追加した科目数
+  updated int not null default 0, -- 更新した科目数
+  unchanged int not null default 0, -- 変更がなかった科目数
+  deleted int not null default 0, -- --sync で削除した科目数
+  skipped int not null default 0, -- 科目番号がないため読み飛ばした行数
+  parse_errors int not null default 0, -- 変換できなかった行数
+  tool_version varchar(64) not null, -- csv2sql のバージョン
+  error text, -- 失敗したときのエラー
+  primary key (id)
+);
+
+-- 最後にその科目を追加・更新した import
+alter table courses add column import_run_id int references import_runs (id) on delete set null;
+
+-- +migrate Down
+
+alter table courses drop column if exists import_run_id;
+drop table if exists import_runs;
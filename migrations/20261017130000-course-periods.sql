@@ -0,0 +1,22 @@
+-- +migrate Up
+
+-- 曜時限の種類（kdb.PeriodKind）
+create type period_kind as enum ('regular', 'intensive', 'by_appointment', 'anytime', 'nt');
+
+-- courses.period_ を 1 コマずつに分けたもの
+create table if not exists course_periods (
+  id serial not null,
+  course_id int not null references courses (id) on delete cascade,
+  day smallint check (day between 1 and 7), -- 曜日（1 が月曜日，extract(isodow from ...) と同じ）。集中などは null
+  slot smallint check (slot >= 1), -- 時限。集中などで時限がなければ null
+  kind period_kind not null, -- regular 以外は集中・応談・随時・NT
+  primary key (id)
+);
+
+create index if not exists course_periods_course_id_idx on course_periods (course_id);
+create index if not exists course_periods_day_slot_idx on course_periods (day, slot);
+
+-- +migrate Down
+
+drop table if exists course_periods;
+drop type if exists period_kind;
@@ -0,0 +1,13 @@
+-- +migrate Up
+
+-- 時間割（config/timetable.yml）から求めた開始・終了時刻
+-- 集中などの時限がないものや，時間割にない時限は null
+alter table course_periods
+  add column if not exists start_time time,
+  add column if not exists end_time time;
+
+-- +migrate Down
+
+alter table course_periods
+  drop column if exists start_time,
+  drop column if exists end_time;
@@ -0,0 +1,68 @@
+-- +migrate Up
+
+-- 学年暦（kdb/calendar）
+-- calendar import で年度ごとに作りなおす
+
+-- 実施学期（courses.term の値）ごとの授業期間
+create table if not exists academic_terms (
+  year int not null,
+  term int not null, -- kdb.TermSpringACode など
+  start_date date not null,
+  end_date date not null,
+  primary key (year, term),
+  check (start_date <= end_date)
+);
+
+-- 休日（振替授業日を除く）
+create table if not exists academic_holidays (
+  year int not null,
+  date date not null,
+  primary key (year, date)
+);
+
+-- 振替授業日（date には day の時間割で授業を行う）
+create table if not exists academic_substitute_days (
+  year int not null,
+  date date not null,
+  day smallint not null check (day between 1 and 7), -- course_periods.day と同じ
+  primary key (year, date)
+);
+
+-- +migrate StatementBegin
+-- 科目が d に授業を行うか（kdb/calendar の Year.Runs と同じ）
+-- 春学期・秋学期・通年はモジュールに分けて授業期間を探す
+create or replace function course_runs_on(course_id int, d date) returns boolean as $$
+  select exists (
+    select 1
+    from courses c
+    join academic_terms t on t.year = c.year
+    join course_periods p on p.course_id = c.id and p.kind = 'regular'
+    where c.id = course_runs_on.course_id
+      and d between t.start_date and t.end_date
+      and t.term in (
+        -- 9: 通年，10: 春学期，11: 秋学期（kdb.TermAllCode など）
+        select unnest(case m.term
+          when 9 then array[1, 2, 3, 4, 5, 6]
+          when 10 then array[1, 2, 3]
+          when 11 then array[4, 5, 6]
+          else array[m.term]
+        end)
+        from unnest(c.term) as m(term)
+      )
+      and p.day = coalesce(
+        (select s.day from academic_substitute_days s where s.year = c.year and s.date = d),
+        case when exists (select 1 from academic_holidays h where h.year = c.year and h.date = d)
+          then null
+          else extract(isodow from d)::smallint
+        end
+      )
+  )
+$$ language sql stable;
+-- +migrate StatementEnd
+
+-- +migrate Down
+
+drop function if exists course_runs_on(int, date);
+drop table if exists academic_substitute_days;
+drop table if exists academic_holidays;
+drop table if exists academic_terms;
@@ -0,0 +1,58 @@
+-- +migrate Up
+
+-- 実施学期（kdb.Terms）
+-- import では更新しないので，実施学期を足すときは 20261017220000 のようにマイグレーションで足す
+create table if not exists terms (
+  code int not null, -- courses.term の値（kdb.TermSpringACode など）
+  label_ja varchar(16) not null, -- 春A など
+  label_en varchar(32) not null, -- Spring A など
+  season varchar(16) not null check (season in ('spring', 'fall', 'summer_vacation', 'spring_vacation', 'all_year')),
+  sort_order int not null, -- 春A, 春B, ..., 秋学期 の順
+  primary key (code),
+  unique (label_ja)
+);
+
+insert into terms (code, label_ja, label_en, season, sort_order) values
+  (1, '春A', 'Spring A', 'spring', 0),
+  (2, '春B', 'Spring B', 'spring', 1),
+  (3, '春C', 'Spring C', 'spring', 2),
+  (4, '秋A', 'Fall A', 'fall', 3),
+  (5, '秋B', 'Fall B', 'fall', 4),
+  (6, '秋C', 'Fall C', 'fall', 5),
+  (7, '夏季休業中', 'Summer Vacation', 'summer_vacation', 6),
+  (8, '春季休業中', 'Spring Vacation', 'spring_vacation', 7),
+  (9, '通年', 'Full Year', 'all_year', 8),
+  (10, '春学期', 'Spring Semester', 'spring', 9),
+  (11, '秋学期', 'Fall Semester', 'fall', 10)
+on conflict do nothing;
+
+-- courses.term を 1 つずつに分けたもの
+create table if not exists course_terms (
+  course_id int not null references courses (id) on delete cascade,
+  term int not null references terms (code),
+  primary key (course_id, term)
+);
+
+create index if not exists course_terms_term_idx on course_terms (term);
+
+insert into course_terms (course_id, term)
+select c.id, t.term
+from courses c cross join lateral unnest(c.term) as t(term)
+where t.term in (select code from terms)
+on conflict do nothing;
+
+-- 移行の間 courses.term の代わりに使う
+-- courses.term を削除した後も同じ形で読めるよう course_terms から作る（実施学期のない科目は空の配列）
+create or replace view course_term_codes as
+select c.id as course_id,
+  coalesce(array_agg(ct.term order by t.sort_order) filter (where ct.term is not null), '{}') as term
+from courses c
+left join course_terms ct on ct.course_id = c.id
+left join terms t on t.code = ct.term
+group by c.id;
+
+-- +migrate Down
+
+drop view if exists course_term_codes;
+drop table if exists course_terms;
+drop table if exists terms;
@@ -0,0 +1,27 @@
+-- +migrate Up
+
+-- 担当教員
+-- 名前は kdb.NormalizeInstructorName で正規化し，別名ファイルがあれば正式な名前にしたもの
+create table if not exists instructors (
+  id serial not null,
+  name varchar(256) not null,
+  primary key (id),
+  unique (name)
+);
+
+-- 科目の担当教員（CSV に書かれた順番）
+-- 既存の科目は次の import で作られる
+create table if not exists course_instructors (
+  course_id int not null references courses (id) on delete cascade,
+  instructor_id int not null references instructors (id),
+  position int not null, -- 1 から
+  primary key (course_id, position),
+  unique (course_id, instructor_id)
+);
+
+create index if not exists course_instructors_instructor_id_idx on course_instructors (instructor_id);
+
+-- +migrate Down
+
+drop table if exists course_instructors;
+drop table if exists instructors;
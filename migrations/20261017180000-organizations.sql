@@ -0,0 +1,23 @@
+-- +migrate Up
+
+-- 科目を開設している組織（学群・学類・専攻など）
+-- import のたびに config/organizations.yml の内容で更新する
+create table if not exists organizations (
+  prefix varchar(8) not null, -- 科目番号の先頭（GB など）
+  name varchar(256) not null,
+  parent_prefix varchar(8) references organizations (prefix) on delete set null, -- 学類なら学群
+  primary key (prefix)
+);
+
+alter table courses
+  add column if not exists organization_prefix varchar(8) references organizations (prefix) on delete set null, -- 科目番号が最も長く一致する組織
+  add column if not exists course_level smallint; -- 科目番号の水準（GB10234 なら 1）
+
+create index if not exists courses_organization_prefix_idx on courses (organization_prefix);
+
+-- +migrate Down
+
+alter table courses
+  drop column if exists organization_prefix,
+  drop column if exists course_level;
+drop table if exists organizations;
@@ -0,0 +1,12 @@
+-- +migrate Up
+
+-- credits（CSV のままの文字列）を数値にしたもの
+-- "?" や空の場合は null
+alter table courses add column if not exists credits_numeric numeric(4,1);
+
+update courses set credits_numeric = trim(credits)::numeric(4,1)
+where trim(credits) ~ '^[0-9]{1,3}(\.[0-9])?$';
+
+-- +migrate Down
+
+alter table courses drop column if exists credits_numeric;
@@ -0,0 +1,30 @@
+-- +migrate Up
+
+-- 授業方法（kdb.InstructionalType）
+-- courses.instructional_type の外部キーの参照先なので，全ての授業方法をここで入れておく（import では更新しない）
+create table if not exists instructional_types (
+  code instructional_type not null,
+  label_ja varchar(64) not null, -- 講義 など
+  label_en varchar(128) not null, -- Lecture など
+  primary key (code)
+);
+
+insert into instructional_types (code, label_ja, label_en) values
+  ('0', 'その他', 'Other'),
+  ('1', '講義', 'Lecture'),
+  ('2', '演習', 'Seminar'),
+  ('3', '実験・実習・実技', 'Experiment, Practicum, Practical Skills'),
+  ('4', '講義及び演習', 'Lecture and Seminar'),
+  ('5', '講義及び実験・実習・実技', 'Lecture and Experiment, Practicum, Practical Skills'),
+  ('6', '演習及び実験・実習・実技', 'Seminar and Experiment, Practicum, Practical Skills'),
+  ('7', '講義，演習及び実験・実習・実技', 'Lecture, Seminar and Experiment, Practicum, Practical Skills'),
+  ('8', '卒業論文・卒業研究等', 'Graduation Thesis, Graduation Research, etc.')
+on conflict do nothing;
+
+alter table courses
+  add constraint courses_instructional_type_fkey foreign key (instructional_type) references instructional_types (code);
+
+-- +migrate Down
+
+alter table courses drop constraint if exists courses_instructional_type_fkey;
+drop table if exists instructional_types;
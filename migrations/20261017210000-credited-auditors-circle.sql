@@ -0,0 +1,9 @@
+-- +migrate Up notransaction
+
+-- ○（kdb.CreditedAuditorsCircle）
+-- PostgreSQL 11 以前ではトランザクションの中で enum に値を足せないため notransaction にしている
+alter type credited_auditors add value if not exists '3';
+
+-- +migrate Down
+
+-- enum から値は削除できないため何もしない
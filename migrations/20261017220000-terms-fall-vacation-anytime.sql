@@ -0,0 +1,27 @@
+-- +migrate Up
+
+-- 秋季休業中（kdb.TermFallVacationCode）と随時（kdb.TermAnytimeCode）
+alter table terms drop constraint if exists terms_season_check;
+alter table terms add constraint terms_season_check
+  check (season in ('spring', 'fall', 'summer_vacation', 'fall_vacation', 'spring_vacation', 'all_year', 'anytime'));
+
+-- 秋季休業中は夏季休業中の後，随時は通年の後に並べる
+update terms set sort_order = sort_order + 1 where code in (8, 9);
+update terms set sort_order = sort_order + 2 where code in (10, 11);
+
+insert into terms (code, label_ja, label_en, season, sort_order) values
+  (12, '秋季休業中', 'Fall Vacation', 'fall_vacation', 7),
+  (13, '随時', 'Anytime', 'anytime', 10)
+on conflict do nothing;
+
+-- +migrate Down
+
+delete from course_terms where term in (12, 13);
+delete from academic_terms where term in (12, 13);
+delete from terms where code in (12, 13);
+update terms set sort_order = sort_order - 1 where code in (8, 9);
+update terms set sort_order = sort_order - 2 where code in (10, 11);
+
+alter table terms drop constraint if exists terms_season_check;
+alter table terms add constraint terms_season_check
+  check (season in ('spring', 'fall', 'summer_vacation', 'spring_vacation', 'all_year'));
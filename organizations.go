@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const defaultOrganizationsFile = "organizations.yml"
+
+// 科目番号の先頭から組織への対応
+type organization struct {
+	Prefix string `yaml:"prefix"`
+	Name   string `yaml:"name"`
+}
+
+// 組織の一覧を読む
+// path が空なら同梱の config/organizations.yml を使う
+func loadOrganizations(path string) ([]organization, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if path == "" {
+		path = "config/" + defaultOrganizationsFile
+		b, err = configBox.Find(defaultOrganizationsFile)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	config := struct {
+		Organizations []organization `yaml:"organizations"`
+	}{}
+	err = yaml.UnmarshalStrict(b, &config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse organizations %s", path)
+	}
+	seen := map[string]bool{}
+	for _, o := range config.Organizations {
+		if o.Prefix == "" || o.Name == "" {
+			return nil, errors.Errorf("%s: organization without prefix or name", path)
+		}
+		if seen[o.Prefix] {
+			return nil, errors.Errorf("%s: duplicated prefix %s", path, o.Prefix)
+		}
+		seen[o.Prefix] = true
+	}
+	return config.Organizations, nil
+}
+
+// 科目番号の先頭 coursePrefix が最も長く一致する組織の prefix（なければ空）
+// updateCourseOrganizations がデータベースで行うものと同じ
+func matchOrganization(organizations []organization, coursePrefix string) string {
+	match := ""
+	for _, o := range organizations {
+		if strings.HasPrefix(coursePrefix, o.Prefix) && len(o.Prefix) > len(match) {
+			match = o.Prefix
+		}
+	}
+	return match
+}
+
+// prefix の親（prefix より短く，最も長く一致する組織）
+func parentOrganization(organizations []organization, prefix string) string {
+	if prefix == "" {
+		return ""
+	}
+	return matchOrganization(organizations, prefix[:len(prefix)-1])
+}
+
+// organizations テーブルを設定ファイルの組織の一覧にする
+// 1 つの insert でまとめて保存するので，親が同じ一覧の後ろにあっても外部キーのエラーにならない
+// 一覧から消えた組織も科目から参照されているかもしれないため削除しない
+func syncOrganizations(tx *sqlx.Tx, organizations []organization) error {
+	prefixes, names, parents := []string{}, []string{}, []string{}
+	for _, o := range organizations {
+		prefixes = append(prefixes, o.Prefix)
+		names = append(names, o.Name)
+		parents = append(parents, parentOrganization(organizations, o.Prefix))
+	}
+	_, err := tx.Exec(`insert into organizations (prefix, name, parent_prefix)
+		select prefix, name, nullif(parent_prefix, '')
+		from unnest($1::text[], $2::text[], $3::text[]) as o(prefix, name, parent_prefix)
+		on conflict (prefix) do update set name = excluded.name, parent_prefix = excluded.parent_prefix`,
+		pq.Array(prefixes), pq.Array(names), pq.Array(parents))
+	return errors.WithStack(err)
+}
+
+// 一時テーブルに含まれる科目を，科目番号の先頭が最も長く一致する組織に結びつけ，水準も設定する
+// 科目番号が読めなかった科目は organization_prefix も course_level も null にする
+func updateCourseOrganizations(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`update courses c
+		set organization_prefix = (
+				select o.prefix from organizations o
+				where s.course_prefix like o.prefix || '%'
+				order by length(o.prefix) desc
+				limit 1
+			),
+			course_level = case when s.course_prefix <> '' then s.course_level end
+		from (` + latestStagedQuery(stagingColumns()) + `) s
+		where c.course_number = s.course_number and c.year = s.year`)
+	return errors.WithStack(err)
+}
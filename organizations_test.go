@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sylms/csv2sql/kdb"
+)
+
+// 同梱の組織の一覧が読めて，学類の親が学群になる
+func Test_loadOrganizations_bundled(t *testing.T) {
+	organizations, err := loadOrganizations("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		prefix string
+		want   string
+	}{
+		{"G", ""},
+		{"GB", "G"},
+		{"GB1", "GB"},
+		{"AB", "A"},
+		{"ZB", ""},
+	}
+	for _, tt := range tests {
+		if got := parentOrganization(organizations, tt.prefix); got != tt.want {
+			t.Errorf("parentOrganization(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+// 同梱の組織の一覧で，いろいろな学群の科目番号が学類に結びつく
+func Test_matchOrganization_bundled(t *testing.T) {
+	organizations, err := loadOrganizations("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		courseNumber string
+		want         string
+	}{
+		{"AB10111", "AB"},
+		{"BC12011", "BC"},
+		{"CB11001", "CB"},
+		{"EB10011", "EB"},
+		{"FH10011", "FH"},
+		{"GA15211", "GA"},
+		{"GB10234", "GB"},
+		{"GE70301", "GE"},
+		{"HC11001", "HC"},
+		{"ＧＢ１０２３４", "GB"},
+		// 学類のない専門学群は学群に結びつく
+		{"WB10011", "W"},
+		{"YB11021", "Y"},
+		// 一覧にない組織
+		{"ZZ10011", ""},
+		{"1227571", ""},
+	}
+	for _, tt := range tests {
+		cn, err := kdb.ParseCourseNumber(tt.courseNumber)
+		if err != nil {
+			t.Errorf("ParseCourseNumber(%q) error = %v", tt.courseNumber, err)
+			continue
+		}
+		if got := matchOrganization(organizations, cn.Prefix); got != tt.want {
+			t.Errorf("matchOrganization(%q) = %q, want %q", cn.Prefix, got, tt.want)
+		}
+	}
+}
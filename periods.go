@@ -0,0 +1,50 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/kdb/timetable"
+)
+
+// 一時テーブルに含まれる科目の曜時限を 1 コマずつ course_periods に入れなおす
+// CSV に書かれた順に id を振り，t があれば時限ごとの開始・終了時刻も保存する
+func replaceCoursePeriods(tx *sqlx.Tx, t *timetable.Timetable) error {
+	_, err := tx.Exec(`delete from course_periods p
+		using courses c, ` + stagingTable + ` s
+		where p.course_id = c.id and c.course_number = s.course_number and c.year = s.year`)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	slots, starts, ends := timetableArrays(t)
+	_, err = tx.Exec(`insert into course_periods (course_id, day, slot, kind, start_time, end_time)
+		select c.id, nullif(p.day, 0), nullif(p.slot, 0), p.kind::period_kind, t.start_time, t.end_time
+		from (`+latestStagedQuery(stagingColumns())+`) s
+		join courses c on c.course_number = s.course_number and c.year = s.year
+		cross join lateral unnest(s.period_days, s.period_slots, s.period_kinds) with ordinality as p(day, slot, kind, ord)
+		left join unnest($1::int[], $2::time[], $3::time[]) as t(slot, start_time, end_time)
+			on p.kind = 'regular' and t.slot = p.slot
+		order by c.id, p.ord`, pq.Array(slots), pq.Array(starts), pq.Array(ends))
+	return errors.WithStack(err)
+}
+
+// 時間割を時限・開始時刻・終了時刻の配列にする
+func timetableArrays(t *timetable.Timetable) (slots []int64, starts []string, ends []string) {
+	slots, starts, ends = []int64{}, []string{}, []string{}
+	if t == nil {
+		return slots, starts, ends
+	}
+	for slot := range t.Slots {
+		slots = append(slots, int64(slot))
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i] < slots[j] })
+	for _, slot := range slots {
+		st := t.Slots[int(slot)]
+		starts = append(starts, st.Start.String())
+		ends = append(ends, st.End.String())
+	}
+	return slots, starts, ends
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/kdbcsv"
+)
+
+// 読み込み → 変換 → 書き込みの各段の間に置くチャネルの大きさ
+// 後ろの段が詰まるとここが埋まって前の段も止まるので，メモリに載るのはおよそこの件数までになる
+const pipelineBufferSize = 256
+
+// CSV から読んだ 1 レコード
+type csvRecord struct {
+	input   string
+	line    int
+	header  []string
+	columns kdbColumns
+	record  []string
+	// input を読んだ文字コード（encodingCP932 など）
+	encoding string
+	// フィールド数が合わないなど，このレコードだけの問題
+	err *kdbcsv.ParseError
+}
+
+// CSV を読みながら変換した科目を順に送るもの
+// courses を最後まで読むか stop を呼んだ後に wait で結果を受け取る
+type courseStream struct {
+	courses <-chan Courses
+	cancel  context.CancelFunc
+	done    chan struct{}
+	stats   loadStats
+	err     error
+}
+
+// CSV の読み込みと変換をそれぞれ goroutine で始める
+// 変換できない行があった場合，policy.skipInvalid でなければ全ての CSV を読み終えてから wait が errInvalidRows を返す
+func startCourseStream(inputs inputOptions, year int, policy validationPolicy) *courseStream {
+	ctx, cancel := context.WithCancel(context.Background())
+	courses := make(chan Courses, pipelineBufferSize)
+	s := &courseStream{
+		courses: courses,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+		defer close(courses)
+		defer cancel()
+		s.stats, s.err = streamCourses(ctx, inputs, year, policy, courses)
+	}()
+	return s
+}
+
+// 読み込みと変換が終わるのを待って結果を返す
+func (s *courseStream) wait() (loadStats, error) {
+	<-s.done
+	return s.stats, s.err
+}
+
+// 書き込み側でエラーになったときなどに，読み込みと変換を途中で止める
+func (s *courseStream) stop() {
+	s.cancel()
+	<-s.done
+}
+
+func streamCourses(ctx context.Context, inputs inputOptions, year int, policy validationPolicy, out chan<- Courses) (loadStats, error) {
+	stats := loadStats{}
+	sources, err := resolveInputs(inputs.patterns)
+	if err != nil {
+		return stats, err
+	}
+
+	names := []string{}
+	for _, source := range sources {
+		names = append(names, source.name)
+	}
+	collector, err := newErrorCollector(policy, names)
+	if err != nil {
+		return stats, err
+	}
+
+	records := make(chan csvRecord, pipelineBufferSize)
+	var (
+		readInputs []inputStats
+		readErr    error
+	)
+	readCtx, cancelRead := context.WithCancel(ctx)
+	defer cancelRead()
+	go func() {
+		defer close(records)
+		readInputs, readErr = readRecords(readCtx, sources, inputs.encoding, records)
+	}()
+
+	err = csvToCoursesStruct(ctx, records, out, year, inputs.workers, &stats, collector)
+	stats.Errors, stats.OmittedErrors = collector.errors, collector.count-len(collector.errors)
+	rejected, rejectFiles, closeErr := collector.close()
+	stats.Rejected, stats.RejectFiles = rejected, rejectFiles
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// 読み込み側を止めてから返す
+		cancelRead()
+		for range records {
+		}
+		return stats, err
+	}
+	// records が閉じられた後なので readInputs, readErr は書き終わっている
+	stats.Inputs = readInputs
+	if readErr != nil {
+		return stats, readErr
+	}
+
+	if stats.ParseErrors > 0 {
+		if !policy.skipInvalid {
+			return stats, errors.Wrapf(errInvalidRows, "%d invalid rows", stats.ParseErrors)
+		}
+		log.Printf("skipped %d invalid rows", stats.ParseErrors)
+	}
+	return stats, nil
+}
+
+// 読み込みの段
+// sources を順に読み，ヘッダー以外のレコードを records に送る
+func readRecords(ctx context.Context, sources []inputSource, encoding string, records chan<- csvRecord) ([]inputStats, error) {
+	inputs := []inputStats{}
+	for _, source := range sources {
+		input, err := readRecordsFromSource(ctx, source, encoding, records)
+		if err != nil {
+			return inputs, errors.WithMessagef(err, "%s", source.name)
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, nil
+}
+
+func readRecordsFromSource(ctx context.Context, source inputSource, encoding string, records chan<- csvRecord) (inputStats, error) {
+	kdbCSV, err := source.open()
+	if err != nil {
+		return inputStats{}, err
+	}
+	defer kdbCSV.Close()
+
+	// どの CSV を取り込んだか後から確認できるよう，読みながらハッシュを計算する
+	hash := sha256.New()
+	in := io.TeeReader(kdbCSV, hash)
+
+	// KdB からダウンロードした CSV は CP932 だが，Excel などで保存しなおしたものは UTF-8 のこともある
+	decoded, encoding, err := newDecodingReader(in, encoding)
+	if err != nil {
+		return inputStats{}, err
+	}
+	log.Printf("%s: reading as %s", source.name, encoding)
+
+	reader := kdbcsv.NewReader(decoded)
+	reader.OnAmbiguity = func(e *kdbcsv.ParseError) {
+		log.Printf("%s: %v", source.name, e)
+	}
+
+	rows, err := sendRecords(ctx, reader, source.name, encoding, records)
+	if err != nil {
+		return inputStats{}, err
+	}
+	log.Printf("%s: read %d rows", source.name, rows)
+
+	// 末尾の空行などが読まれずに残っていてもハッシュはファイル全体から計算する
+	_, err = io.Copy(io.Discard, in)
+	if err != nil {
+		return inputStats{}, errors.WithStack(err)
+	}
+	return inputStats{Name: source.name, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// ヘッダーを読んでから残りのレコードを records に送る
+// 空のファイルは 0 件とする
+func sendRecords(ctx context.Context, reader *kdbcsv.Reader, input string, encoding string, records chan<- csvRecord) (int, error) {
+	header, err := reader.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	columns, err := newKdbColumns(header)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		r := csvRecord{input: input, encoding: encoding, line: reader.Line(), header: header, columns: columns, record: record}
+		// フィールド数が合わない行や閉じていないダブルクォーテーションはその行だけの問題として扱う
+		if err != nil && !errors.As(err, &r.err) {
+			return rows, errors.WithStack(err)
+		}
+
+		select {
+		case records <- r:
+		case <-ctx.Done():
+			return rows, errors.WithStack(ctx.Err())
+		}
+		rows++
+	}
+}
+
+// 変換の段
+// records を KdbExportCSV に読み込んで DB 向けの Courses に変換し，out に送る
+// 変換は workers 個の goroutine で行うが，out に送る順番と collector に記録する順番は records の順番のままにする
+// 変換できない行は collector に記録して読み飛ばす
+func csvToCoursesStruct(ctx context.Context, records <-chan csvRecord, out chan<- Courses, year int, workers int, stats *loadStats, collector *errorCollector) error {
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// records の順番に結果を受け取るチャネルを並べる
+	// 先頭の変換が終わっていなければ後ろが終わっていても待つ
+	jobs := make(chan convertJob)
+	pending := make(chan chan []convertResult, workers*2)
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for {
+			chunk, ok := receiveChunk(ctx, records)
+			if !ok {
+				return
+			}
+
+			result := make(chan []convertResult, 1)
+			select {
+			case pending <- result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- convertJob{records: chunk, result: result}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				results := make([]convertResult, 0, len(job.records))
+				for _, r := range job.records {
+					results = append(results, convertRecord(r, year))
+				}
+				job.result <- results
+			}
+		}()
+	}
+
+	for result := range pending {
+		var results []convertResult
+		select {
+		case results = <-result:
+		case <-ctx.Done():
+			return errors.WithStack(ctx.Err())
+		}
+
+		for _, res := range results {
+			if res.skipped {
+				stats.Skipped++
+				continue
+			}
+			if len(res.fieldErrors) > 0 {
+				stats.ParseErrors++
+				err := collector.add(res.record, res.courseNumber, res.fieldErrors)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if res.course.CreditsUnknown {
+				stats.UnknownCredits++
+			}
+
+			select {
+			case out <- res.course:
+			case <-ctx.Done():
+				return errors.WithStack(ctx.Err())
+			}
+		}
+	}
+	// 途中で止められた場合は pending が閉じられるのでエラーにする
+	return errors.WithStack(ctx.Err())
+}
+
+// 1 つの goroutine にまとめて渡す最大の行数
+// 1 行ずつ渡すとチャネルの受け渡しの方が変換より重くなる
+const convertChunkSize = 64
+
+// records から convertChunkSize 件までを受け取る
+// 最初の 1 件は届くまで待つが，それ以降は既に届いている分だけにする
+// records が閉じられているか ctx が終わっていれば false を返す
+func receiveChunk(ctx context.Context, records <-chan csvRecord) ([]csvRecord, bool) {
+	chunk := make([]csvRecord, 0, convertChunkSize)
+	select {
+	case r, ok := <-records:
+		if !ok {
+			return nil, false
+		}
+		chunk = append(chunk, r)
+	case <-ctx.Done():
+		return nil, false
+	}
+	for len(chunk) < convertChunkSize {
+		select {
+		case r, ok := <-records:
+			if !ok {
+				return chunk, true
+			}
+			chunk = append(chunk, r)
+		default:
+			return chunk, true
+		}
+	}
+	return chunk, true
+}
+
+type convertJob struct {
+	records []csvRecord
+	result  chan<- []convertResult
+}
+
+// 1 レコードを変換した結果
+type convertResult struct {
+	record       csvRecord
+	courseNumber string
+	course       Courses
+	// 科目番号がないため読み飛ばす
+	skipped     bool
+	fieldErrors []fieldError
+}
+
+func convertRecord(r csvRecord, year int) convertResult {
+	if r.err != nil {
+		return convertResult{record: r, courseNumber: r.columns.courseNumber(r.record), fieldErrors: []fieldError{{Err: r.err}}}
+	}
+
+	row := r.columns.decode(r.record)
+	// 科目番号がないものは、それは科目ではないとみなしデータベースに投入しないようにする
+	if row.CourseNumber == "" {
+		return convertResult{record: r, skipped: true}
+	}
+
+	course, fieldErrors := convertRow(row, year)
+	return convertResult{record: r, courseNumber: row.CourseNumber, course: course, fieldErrors: fieldErrors}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// n 件の科目を含む CSV を書いて inputOptions を返す
+func writeTestCSV(t *testing.T, n int) inputOptions {
+	rows := [][]string{}
+	for i := 0; i < n; i++ {
+		rows = append(rows, kdbRow(fmt.Sprintf("GB%05d", i), "月1,2", "×", "2022-02-01 10:00:00"))
+	}
+	path := filepath.Join(t.TempDir(), "kdb.csv")
+	err := ioutil.WriteFile(path, []byte(kdbCSV(rows...)), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inputOptions{patterns: []string{path}, encoding: encodingAuto}
+}
+
+// 複数の goroutine で変換しても CSV の順番のまま送る
+func Test_courseStream_order(t *testing.T) {
+	const n = pipelineBufferSize * 4
+	inputs := writeTestCSV(t, n)
+	inputs.workers = 8
+	stream := startCourseStream(inputs, 2022, validationPolicy{})
+	i := 0
+	for c := range stream.courses {
+		if want := fmt.Sprintf("GB%05d", i); c.CourseNumber != want {
+			t.Fatalf("course %d = %s, want %s", i, c.CourseNumber, want)
+		}
+		i++
+	}
+	stats, err := stream.wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != n || len(stats.Inputs) != 1 {
+		t.Errorf("read %d courses from %d inputs, want %d from 1", i, len(stats.Inputs), n)
+	}
+}
+
+// 書き込み側が止まっても読み込み側は待ち続けず，stop で終わる
+func Test_courseStream_stop(t *testing.T) {
+	stream := startCourseStream(writeTestCSV(t, pipelineBufferSize*4), 2022, validationPolicy{})
+	<-stream.courses
+
+	stopped := make(chan struct{})
+	go func() {
+		stream.stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		t.Fatal("stop() did not return")
+	}
+	if _, err := stream.wait(); err == nil {
+		t.Error("wait() error = nil after stop()")
+	}
+}
+
+// 変換できない行のエラーも CSV の順番のまま記録する
+func Test_csvToCoursesStruct_errorOrder(t *testing.T) {
+	rows := [][]string{}
+	for i := 0; i < 100; i++ {
+		creditedAuditors := "×"
+		if i%3 == 0 {
+			creditedAuditors = "?"
+		}
+		rows = append(rows, kdbRow(fmt.Sprintf("GB%05d", i), "月1,2", creditedAuditors, "2022-02-01 10:00:00"))
+	}
+	path := filepath.Join(t.TempDir(), "kdb.csv")
+	if err := ioutil.WriteFile(path, []byte(kdbCSV(rows...)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, stats, err := loadCourses(inputOptions{patterns: []string{path}, encoding: encodingUTF8, workers: 8}, 2022, validationPolicy{skipInvalid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Errors) != 34 {
+		t.Fatalf("got %d errors, want 34", len(stats.Errors))
+	}
+	for i, e := range stats.Errors {
+		// ヘッダーが 1 行目なので i*3 番目の科目は i*3+2 行目
+		if want := fmt.Sprintf("GB%05d", i*3); e.CourseNumber != want || e.Line != i*3+2 {
+			t.Errorf("error %d = %s (line %d), want %s (line %d)", i, e.CourseNumber, e.Line, want, i*3+2)
+		}
+	}
+}
+
+// エラーは maxKeptErrors 件まで残し，それ以上は数えるだけにする
+func Test_csvToCoursesStruct_errorLimit(t *testing.T) {
+	const n = maxKeptErrors + 100
+	rows := [][]string{}
+	for i := 0; i < n; i++ {
+		rows = append(rows, kdbRow(fmt.Sprintf("GB%05d", i), "月1,2", "?", "2022-02-01 10:00:00"))
+	}
+	_, stats, err := loadTestCSV(t, kdbCSV(rows...), validationPolicy{skipInvalid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Errors) != maxKeptErrors || stats.OmittedErrors != n-maxKeptErrors || stats.ParseErrors != n {
+		t.Errorf("kept %d errors, omitted %d, %d invalid rows, want %d, %d, %d",
+			len(stats.Errors), stats.OmittedErrors, stats.ParseErrors, maxKeptErrors, n-maxKeptErrors, n)
+	}
+}
+
+func benchmarkCsvToCoursesStruct(b *testing.B, workers int) {
+	columns, err := newKdbColumns(kdbHeader)
+	if err != nil {
+		b.Fatal(err)
+	}
+	records := []csvRecord{}
+	for _, c := range syntheticCourses(20000, 2022) {
+		row := kdbRow(c.CourseNumber, "月1・2,水3-5", "×", "2022-02-01 10:00:00")
+		row[5] = "春AB 秋ABC"
+		records = append(records, csvRecord{input: "kdb.csv", header: kdbHeader, columns: columns, record: row})
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in := make(chan csvRecord, pipelineBufferSize)
+		out := make(chan Courses, pipelineBufferSize)
+		go func() {
+			defer close(in)
+			for _, r := range records {
+				in <- r
+			}
+		}()
+		go func() {
+			for range out {
+			}
+		}()
+		err := csvToCoursesStruct(context.Background(), in, out, 2022, workers, &loadStats{}, &errorCollector{})
+		close(out)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCsvToCoursesStruct_workers1(b *testing.B) {
+	benchmarkCsvToCoursesStruct(b, 1)
+}
+
+func BenchmarkCsvToCoursesStruct_workers4(b *testing.B) {
+	benchmarkCsvToCoursesStruct(b, 4)
+}
+
+// -cpu 1,2,4 のように指定すると CPU 数ごとに比較できる
+func BenchmarkCsvToCoursesStruct_workersGOMAXPROCS(b *testing.B) {
+	benchmarkCsvToCoursesStruct(b, runtime.GOMAXPROCS(0))
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/kdbcsv"
+	"golang.org/x/text/transform"
+)
+
+// reject ファイルでエラーを書くカラム
+// KdbExportCSV にないカラムなので，直した reject ファイルをそのまま import に渡せる
+const rejectErrorColumn = "エラー"
+
+// 変換できなかった行
+type rejectedRow struct {
+	// 元の CSV のヘッダーとレコード
+	Header []string
+	Record []string
+	// 元の CSV を読んだ文字コード
+	Encoding string
+	Errors   []rowError
+}
+
+// 変換できなかった行を，元の CSV と同じヘッダーに rejectErrorColumn を足して書き出すもの
+// 見つかったそばから書き出し，変換できなかった行をメモリに溜めない
+// CSV ごとにヘッダーや文字コードが違ってもよいよう，reject ファイルは読み込む CSV ごとに分ける
+type rejectWriter struct {
+	// 読み込む CSV の名前ごとの reject ファイルのパス
+	paths map[string]string
+	// 書き出した行数
+	rows  int
+	files map[string]*rejectFile
+}
+
+// 1 つの CSV の変換できなかった行を書き出すファイル
+// ファイルは最初の行を書くときに作り，文字コードはその CSV を読んだときと同じにする
+// そのまま import に渡せるよう元のレコードは書き換えず，書き出せない文字があればエラーにする
+type rejectFile struct {
+	path     string
+	f        *os.File
+	out      io.WriteCloser
+	w        *kdbcsv.Writer
+	encoding string
+	encoder  transform.Transformer
+}
+
+// inputs は読み込む CSV の名前（inputSource.name）
+// reject ファイルが読み込む CSV と同じファイルになる場合はエラーにする（読んでいる途中で空にしてしまうため）
+func newRejectWriter(path string, inputs []string) (*rejectWriter, error) {
+	paths := rejectFilePaths(path, inputs)
+	for _, input := range inputs {
+		if input == stdinInputName {
+			continue
+		}
+		for _, p := range paths {
+			if sameFile(p, input) {
+				return nil, errors.Errorf("reject file %s is also an input", p)
+			}
+		}
+	}
+	return &rejectWriter{paths: paths, files: map[string]*rejectFile{}}, nil
+}
+
+// CSV ごとの reject ファイルのパス
+// CSV が 1 つなら path のまま，複数なら reject.csv と kdb-2021.csv から reject-kdb-2021.csv のように作る
+func rejectFilePaths(path string, inputs []string) map[string]string {
+	paths := map[string]string{}
+	if len(inputs) == 1 {
+		paths[inputs[0]] = path
+		return paths
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	used := map[string]bool{}
+	for _, input := range inputs {
+		name := "stdin"
+		if input != stdinInputName {
+			name = strings.TrimSuffix(filepath.Base(input), filepath.Ext(input))
+		}
+		// 別のディレクトリに同じ名前の CSV があれば番号を付ける
+		p := base + "-" + name + ext
+		for i := 2; used[p]; i++ {
+			p = fmt.Sprintf("%s-%s-%d%s", base, name, i, ext)
+		}
+		used[p] = true
+		paths[input] = p
+	}
+	return paths
+}
+
+// a と b が同じファイルを指すか（まだないファイルはパスで比べる）
+func sameFile(a, b string) bool {
+	ai, aErr := os.Stat(a)
+	bi, bErr := os.Stat(b)
+	if aErr == nil && bErr == nil {
+		return os.SameFile(ai, bi)
+	}
+	a, aErr = filepath.Abs(a)
+	b, bErr = filepath.Abs(b)
+	return aErr == nil && bErr == nil && a == b
+}
+
+func (r *rejectWriter) write(reject rejectedRow) error {
+	input := reject.Errors[0].Input
+	file, ok := r.files[input]
+	if !ok {
+		path, ok := r.paths[input]
+		if !ok {
+			return errors.Errorf("%s: no reject file for this input", input)
+		}
+		var err error
+		file, err = openRejectFile(path, reject)
+		if err != nil {
+			return err
+		}
+		r.files[input] = file
+	}
+
+	for i, field := range reject.Record {
+		if !encodable(file.encoder, field) {
+			column := strconv.Itoa(i + 1)
+			if i < len(reject.Header) {
+				column = reject.Header[i]
+			}
+			return errors.Errorf("%s line %d: %s cannot be written in %s", input, reject.Errors[0].Line, column, file.encoding)
+		}
+	}
+	record, errorColumn := rejectRecord(reject)
+	// エラーメッセージは元の CSV にないので，書き出せない文字は ? にする
+	record[errorColumn] = replaceUnencodable(file.encoder, record[errorColumn])
+	err := file.w.Write(record)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	r.rows++
+	return nil
+}
+
+// 書き出したファイルの一覧（名前順）
+func (r *rejectWriter) written() []string {
+	paths := []string{}
+	for _, file := range r.files {
+		paths = append(paths, file.path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ファイルを作ってヘッダーを書く
+func openRejectFile(path string, reject rejectedRow) (*rejectFile, error) {
+	encoder, err := newEncoder(reject.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	file := &rejectFile{path: path, f: f, encoding: reject.Encoding, encoder: encoder}
+	file.out = nopWriteCloser{f}
+	if encoder != nil {
+		file.out = transform.NewWriter(f, encoder)
+	}
+	file.w = kdbcsv.NewWriter(file.out)
+	err = file.w.Write(append(append([]string{}, reject.Header...), rejectErrorColumn))
+	if err != nil {
+		f.Close()
+		return nil, errors.WithStack(err)
+	}
+	return file, nil
+}
+
+// 書き出したファイルを全て閉じる
+func (r *rejectWriter) close() error {
+	var firstErr error
+	for _, file := range r.files {
+		err := file.close()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (file *rejectFile) close() error {
+	defer file.f.Close()
+	err := file.w.Flush()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	err = file.out.Close()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(file.f.Close())
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// encoder（nil なら UTF-8）で s を書き出せるか
+func encodable(encoder transform.Transformer, s string) bool {
+	if encoder == nil {
+		return true
+	}
+	_, _, err := transform.String(encoder, s)
+	return err == nil
+}
+
+// encoder で書き出せない文字を ? にする
+func replaceUnencodable(encoder transform.Transformer, s string) string {
+	if encodable(encoder, s) {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if !encodable(encoder, string(r)) {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+// 元のレコードにエラーを足し，エラーを入れたカラムの番号とともに返す
+// エラーはヘッダーの rejectErrorColumn と同じ位置に入れる
+// フィールドが足りないレコードは空のフィールドで埋め，多すぎるレコードはあふれた分をエラーの後ろに残す
+func rejectRecord(reject rejectedRow) ([]string, int) {
+	n := len(reject.Header)
+	record := append([]string{}, reject.Record...)
+	for len(record) < n {
+		record = append(record, "")
+	}
+
+	messages := []string{}
+	for _, e := range reject.Errors {
+		if e.Column == "" {
+			messages = append(messages, e.Message)
+			continue
+		}
+		messages = append(messages, e.Column+": "+e.Message)
+	}
+	withError := append(append([]string{}, record[:n]...), strings.Join(messages, "; "))
+	return append(withError, record[n:]...), n
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sylms/csv2sql/kdbcsv"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+)
+
+// csv を encoding のファイルに書いて loadCourses で読む
+func loadTestCSVAs(t *testing.T, csv string, encoding string, policy validationPolicy) ([]Courses, loadStats, error) {
+	encoder, err := newEncoder(encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoder != nil {
+		csv, _, err = transform.String(encoder, csv)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "kdb.csv")
+	err = ioutil.WriteFile(path, []byte(csv), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loadCourses(inputOptions{patterns: []string{path}, encoding: encoding}, 2022, policy)
+}
+
+// reject ファイルは元のヘッダーにエラーのカラムを足した，元と同じ文字コードの CSV で，そのまま読み直せる
+func Test_rejectWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		// 科目名
+		courseName string
+		decoder    transform.Transformer
+	}{
+		{
+			name:       "CP932",
+			encoding:   encodingCP932,
+			courseName: "①情報科学特論～",
+			decoder:    japanese.ShiftJIS.NewDecoder(),
+		},
+		{
+			name:       "JIS X 0208 の対応付け",
+			encoding:   encodingShiftJIS,
+			courseName: "情報科学特論〜",
+			decoder:    transform.Chain(japanese.ShiftJIS.NewDecoder(), runes.Map(cp932ToJIS)),
+		},
+		{
+			// Shift_JIS にない文字も書き換えずに残す
+			name:       "UTF-8",
+			encoding:   encodingUTF8,
+			courseName: "𠮷野の情報科学特論",
+			decoder:    transform.Nop,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bad := withColumn(kdbRow("GB10234", "月1,2", "?", "2022/02/01"), "科目名", tt.courseName)
+			csv := kdbCSV(
+				bad,
+				kdbRow("GB10244", "月1,2", "×", "2022-02-01 10:00:00"),
+			) + "\"GB10254\",\"情報科学特論\"\r\n"
+
+			path := filepath.Join(t.TempDir(), "reject.csv")
+			_, stats, err := loadTestCSVAs(t, csv, tt.encoding, validationPolicy{skipInvalid: true, rejectFile: path})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stats.Rejected != 2 {
+				t.Errorf("Rejected = %d, want 2", stats.Rejected)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			records, err := kdbcsv.NewReader(transform.NewReader(f, tt.decoder)).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(records) != 3 {
+				t.Fatalf("got %d records, want 3", len(records))
+			}
+			if want := append(append([]string{}, kdbHeader...), rejectErrorColumn); !reflect.DeepEqual(records[0], want) {
+				t.Errorf("header = %q, want %q", records[0], want)
+			}
+			if got := records[1][:len(bad)]; !reflect.DeepEqual(got, bad) {
+				t.Errorf("record = %q, want %q", got, bad)
+			}
+			if got := records[1][len(bad)]; !strings.HasPrefix(got, "科目等履修生申請可否: ") || !strings.Contains(got, "; データ更新日: ") {
+				t.Errorf("error column = %q", got)
+			}
+			// フィールドが足りないレコードはエラーのカラムが最後にくるよう埋める
+			if got := records[2]; len(got) != len(kdbHeader)+1 || got[0] != "GB10254" {
+				t.Errorf("short record = %q", got)
+			}
+		})
+	}
+}
+
+// 元のレコードは書き換えず，書き出せなければエラーにする
+func Test_rejectWriter_unencodable(t *testing.T) {
+	reject := rejectedRow{
+		Header:   []string{"科目番号", "科目名"},
+		Record:   []string{"GB10234", "𠮷野"},
+		Encoding: encodingCP932,
+		Errors:   []rowError{{Input: "kdb.csv", Line: 2, Message: "𠮷"}},
+	}
+	w, err := newRejectWriter(filepath.Join(t.TempDir(), "reject.csv"), []string{"kdb.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.write(reject)
+	if err == nil || !strings.Contains(err.Error(), "科目名") {
+		t.Errorf("write() error = %v, want an error about 科目名", err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// エラーメッセージだけなら ? にして書き出す
+	reject.Record = []string{"GB10234", "吉野"}
+	path := filepath.Join(t.TempDir(), "reject.csv")
+	w, err = newRejectWriter(path, []string{"kdb.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.write(reject); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := japanese.ShiftJIS.NewDecoder().String(string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"科目番号\",\"科目名\",\"エラー\"\r\n\"GB10234\",\"吉野\",\"?\"\r\n"; got != want {
+		t.Errorf("reject file = %q, want %q", got, want)
+	}
+}
+
+// CSV ごとにヘッダーや文字コードが違っても，それぞれの reject ファイルに書き出す
+func Test_rejectWriter_inputs(t *testing.T) {
+	dir := t.TempDir()
+	bad := kdbRow("GB10234", "月1,2", "?", "2022-02-01 10:00:00")
+
+	utf8Path := filepath.Join(dir, "kdb-2021.csv")
+	if err := ioutil.WriteFile(utf8Path, []byte(kdbCSV(bad)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 知らないカラムが 1 つ多い CP932 の CSV
+	header := append(append([]string{}, kdbHeader...), "メモ")
+	cp932, err := japanese.ShiftJIS.NewEncoder().String(strings.Join([]string{
+		`"` + strings.Join(header, `","`) + `"`,
+		`"` + strings.Join(append(append([]string{}, bad...), "メモ"), `","`) + `"`,
+	}, "\r\n") + "\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp932Path := filepath.Join(dir, "kdb-2022.csv")
+	if err := ioutil.WriteFile(cp932Path, []byte(cp932), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rejectPath := filepath.Join(t.TempDir(), "reject.csv")
+	_, stats, err := loadCourses(inputOptions{patterns: []string{utf8Path, cp932Path}, encoding: encodingAuto}, 2022,
+		validationPolicy{skipInvalid: true, rejectFile: rejectPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(filepath.Dir(rejectPath), "reject-kdb-2021.csv"), filepath.Join(filepath.Dir(rejectPath), "reject-kdb-2022.csv")}
+	if stats.Rejected != 2 || !reflect.DeepEqual(stats.RejectFiles, want) {
+		t.Fatalf("Rejected = %d in %q, want 2 in %q", stats.Rejected, stats.RejectFiles, want)
+	}
+
+	tests := []struct {
+		path    string
+		decoder transform.Transformer
+		header  []string
+	}{
+		{want[0], transform.Nop, kdbHeader},
+		{want[1], japanese.ShiftJIS.NewDecoder(), header},
+	}
+	for _, tt := range tests {
+		f, err := os.Open(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records, err := kdbcsv.NewReader(transform.NewReader(f, tt.decoder)).ReadAll()
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || !reflect.DeepEqual(records[0], append(append([]string{}, tt.header...), rejectErrorColumn)) {
+			t.Errorf("%s = %q", tt.path, records)
+		}
+	}
+}
+
+// 読み込む CSV に reject ファイルを書かない
+func Test_newRejectWriter_input(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "reject-kdb.csv")
+	if err := ioutil.WriteFile(input, []byte(kdbCSV()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		path   string
+		inputs []string
+	}{
+		{name: "same path", path: input, inputs: []string{input}},
+		{name: "relative path", path: filepath.Join(dir, ".", "reject-kdb.csv"), inputs: []string{input}},
+		// CSV が複数なら reject-kdb.csv に書き出す
+		{name: "path for one of the inputs", path: filepath.Join(dir, "reject.csv"), inputs: []string{input, filepath.Join(dir, "kdb.csv")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newRejectWriter(tt.path, tt.inputs); err == nil {
+				t.Error("newRejectWriter() error = nil, want an error")
+			}
+		})
+	}
+	if _, err := newRejectWriter(filepath.Join(dir, "reject.csv"), []string{input, stdinInputName}); err != nil {
+		t.Errorf("newRejectWriter() error = %v", err)
+	}
+}
+
+func Test_rejectRecord(t *testing.T) {
+	header := []string{"科目番号", "科目名"}
+	errs := []rowError{{Column: "科目名", Message: "invalid"}}
+	tests := []struct {
+		name            string
+		record          []string
+		want            []string
+		wantErrorColumn int
+	}{
+		{name: "same length", record: []string{"GB10234", "a"}, want: []string{"GB10234", "a", "科目名: invalid"}, wantErrorColumn: 2},
+		{name: "short record", record: []string{"GB10234"}, want: []string{"GB10234", "", "科目名: invalid"}, wantErrorColumn: 2},
+		// あふれたフィールドはエラーの後ろに残す
+		{name: "long record", record: []string{"GB10234", "a", "b"}, want: []string{"GB10234", "a", "科目名: invalid", "b"}, wantErrorColumn: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errorColumn := rejectRecord(rejectedRow{Header: header, Record: tt.record, Errors: errs})
+			if !reflect.DeepEqual(got, tt.want) || errorColumn != tt.wantErrorColumn {
+				t.Errorf("rejectRecord() = %q, %d, want %q, %d", got, errorColumn, tt.want, tt.wantErrorColumn)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// 科目の変更履歴 1 件
+type courseRevision struct {
+	ID           int    `json:"id"`
+	CourseNumber string `json:"course_number"`
+	Year         int    `json:"year"`
+	// {"カラム名": {"old": 変更前, "new": 変更後}}
+	Changes      map[string]json.RawMessage `json:"changes"`
+	CSVUpdatedAt time.Time                  `json:"csv_updated_at"`
+	CreatedAt    time.Time                  `json:"created_at"`
+}
+
+// 一時テーブルの科目と保存されている科目を比べ，変わったカラムを course_revisions に記録する
+// upsert で上書きされる前に呼ぶ必要がある
+func recordRevisions(tx *sqlx.Tx) (int, error) {
+	changes := []string{}
+	for _, column := range comparedColumns() {
+		changes = append(changes, `'`+column+`', case when c.`+column+` is distinct from s.`+column+`
+			then jsonb_build_object('old', to_jsonb(c.`+column+`), 'new', to_jsonb(s.`+column+`)) end`)
+	}
+
+	// jsonb_strip_nulls で変わらなかったカラムを取り除く
+	res, err := tx.Exec(`insert into course_revisions (course_id, changes, csv_updated_at, created_at)
+		select id, changes, csv_updated_at, $1::timestamp with time zone from (
+			select c.id, s.csv_updated_at, jsonb_strip_nulls(jsonb_build_object(`+strings.Join(changes, ", ")+`)) as changes
+			from courses c
+			join (`+latestStagedQuery(courseColumns)+`) s on s.course_number = c.course_number and s.year = c.year
+		) diff
+		where changes <> '{}'::jsonb`, now)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	recorded, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return int(recorded), nil
+}
+
+// 科目番号の変更履歴を古い順に取得する
+// year が 0 の場合は全ての年度のものを返す
+func selectRevisions(db *sqlx.DB, courseNumber string, year int) ([]courseRevision, error) {
+	rows, err := db.Queryx(`select r.id, c.course_number, c.year, r.changes, r.csv_updated_at, r.created_at
+		from course_revisions r
+		join courses c on c.id = r.course_id
+		where c.course_number = $1 and ($2 = 0 or c.year = $2)
+		order by c.year, r.created_at, r.id`, courseNumber, year)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	revisions := []courseRevision{}
+	for rows.Next() {
+		r := courseRevision{}
+		var changes []byte
+		err := rows.Scan(&r.ID, &r.CourseNumber, &r.Year, &changes, &r.CSVUpdatedAt, &r.CreatedAt)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := json.Unmarshal(changes, &r.Changes); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return revisions, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// 一時テーブルに含まれる科目の実施学期を course_terms に 1 つずつ入れなおす
+// courses.term と同じ番号で，terms にない番号があれば外部キーのエラーになる
+func replaceCourseTerms(tx *sqlx.Tx) error {
+	_, err := tx.Exec(`delete from course_terms t
+		using courses c, ` + stagingTable + ` s
+		where t.course_id = c.id and c.course_number = s.course_number and c.year = s.year`)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = tx.Exec(`insert into course_terms (course_id, term)
+		select c.id, t.term
+		from (` + latestStagedQuery(courseColumns) + `) s
+		join courses c on c.course_number = s.course_number and c.year = s.year
+		cross join lateral unnest(s.term) as t(term)
+		on conflict do nothing`)
+	return errors.WithStack(err)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/gobuffalo/packr/v2"
+	"github.com/pkg/errors"
+	"github.com/sylms/csv2sql/kdb/timetable"
+)
+
+// 同梱する設定ファイル
+var configBox = packr.New("config", "./config")
+
+const defaultTimetableFile = "timetable.yml"
+
+// year に使う時間割を読む
+// path が空なら同梱の config/timetable.yml を使う
+// その年度の時間割がなければ nil を返す（時刻は保存しない）
+func loadTimetable(path string, year int) (*timetable.Timetable, error) {
+	var (
+		b   []byte
+		err error
+	)
+	if path == "" {
+		path = "config/" + defaultTimetableFile
+		b, err = configBox.Find(defaultTimetableFile)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	config, err := timetable.Parse(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse timetable %s", path)
+	}
+	t, ok := config.ForYear(year)
+	if !ok {
+		log.Printf("warning: %s has no timetable for %d, start and end times are not stored", path, year)
+		return nil, nil
+	}
+	return &t, nil
+}
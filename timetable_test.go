@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// 同梱の時間割が読めて，今の年度の時間割がある
+func Test_loadTimetable_bundled(t *testing.T) {
+	got, err := loadTimetable("", 2022)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == nil || len(got.Slots) == 0 {
+		t.Fatalf("loadTimetable() = %v, want timetable for 2022", got)
+	}
+	if st := got.Slots[1]; st.Start.String() != "08:40" || st.End.String() != "09:55" {
+		t.Errorf("slot 1 = %v-%v, want 08:40-09:55", st.Start, st.End)
+	}
+}
+
+// --timetable で渡した時間割から年度に合うものを選ぶ
+func Test_loadTimetable_versions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timetable.yml")
+	err := ioutil.WriteFile(path, []byte(`timetables:
+  - from: 2019
+    slots:
+      1: {start: "8:40", end: "10:10"}
+  - from: 2021
+    slots:
+      1: {start: "8:40", end: "9:55"}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		year int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantEnd string
+	}{
+		{name: "before the first timetable", args: args{year: 2018}},
+		{name: "older timetable", args: args{year: 2020}, wantEnd: "10:10"},
+		{name: "newer timetable", args: args{year: 2021}, wantEnd: "09:55"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadTimetable(path, tt.args.year)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantEnd == "" {
+				if got != nil {
+					t.Errorf("loadTimetable() = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Slots[1].End.String() != tt.wantEnd {
+				t.Errorf("loadTimetable() = %v, want slot 1 ending at %s", got, tt.wantEnd)
+			}
+		})
+	}
+}
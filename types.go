@@ -2,13 +2,15 @@ package main
 
 import (
 	"time"
+
+	"github.com/sylms/csv2sql/kdb"
 )
 
 // KdB から csv でエクスポートしたもの
 type KdbExportCSV struct {
 	CourseNumber      string `csv:"科目番号"`
 	CourseName        string `csv:"科目名"`
-	InstructionalType int    `csv:"授業方法"`
+	InstructionalType string `csv:"授業方法"`
 	// '?' があるため
 	Credits                  string `csv:"単位数"`
 	StandardRegistrationYear string `csv:"標準履修年次"`
@@ -30,30 +32,42 @@ type KdbExportCSV struct {
 }
 
 type Courses struct {
-	ID           int    `db:"id"`
-	CourseNumber string `db:"course_number"`
-	CourseName   string `db:"course_name"`
-	// 対応付けを別に持つ
-	InstructionalType        int      `db:"instructional_type"`
-	Credits                  string   `db:"credits"`
-	StandardRegistrationYear []string `db:"standard_registration_year"`
+	ID           int    `db:"id" json:"id"`
+	CourseNumber string `db:"course_number" json:"course_number"`
+	CourseName   string `db:"course_name" json:"course_name"`
+	// ラベルは kdb.InstructionalType（instructional_types テーブル）にある
+	InstructionalType        kdb.InstructionalType `db:"instructional_type" json:"instructional_type"`
+	Credits                  string                `db:"credits" json:"credits"`
+	StandardRegistrationYear []string              `db:"standard_registration_year" json:"standard_registration_year"`
 	// 対応付けを別に持つ
-	Term []int `db:"term"`
+	Term []int `db:"term" json:"term"`
 	// 例：月1, 月2
-	Period         []string `db:"period_"`
-	Classroom      string   `db:"classroom"`
-	Instructor     []string `db:"instructor"`
-	CourseOverview string   `db:"course_overview"`
-	Remarks        string   `db:"remarks"`
-	// 0 = 'x', 1 = 三角, 2 = ''
-	CreditedAuditors      int    `db:"credited_auditors"`
-	ApplicationConditions string `db:"application_conditions"`
-	AltCourseName         string `db:"alt_course_name"`
-	CourseCode            string `db:"course_code"`
-	CourseCodeName        string `db:"course_code_name"`
+	Period         []string `db:"period_" json:"period"`
+	Classroom      string   `db:"classroom" json:"classroom"`
+	Instructor     []string `db:"instructor" json:"instructor"`
+	CourseOverview string   `db:"course_overview" json:"course_overview"`
+	Remarks        string   `db:"remarks" json:"remarks"`
+	// 0 = 'x', 1 = 三角, 2 = '', 3 = 丸
+	CreditedAuditors      kdb.CreditedAuditors `db:"credited_auditors" json:"credited_auditors"`
+	ApplicationConditions string               `db:"application_conditions" json:"application_conditions"`
+	AltCourseName         string               `db:"alt_course_name" json:"alt_course_name"`
+	CourseCode            string               `db:"course_code" json:"course_code"`
+	CourseCodeName        string               `db:"course_code_name" json:"course_code_name"`
 	// CSV 上にある「データ更新日」
-	CSVUpdatedAt time.Time `db:"csv_updated_at"`
-	Year         int       `db:"year"`
-	CreatedAt    time.Time `db:"created_at"`
-	UpdatedAt    time.Time `db:"updated_at"`
+	CSVUpdatedAt time.Time `db:"csv_updated_at" json:"csv_updated_at"`
+	Year         int       `db:"year" json:"year"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+
+	// 以下は courses には保存せず，一時テーブルを通して関連テーブルに保存する
+
+	// Period を kdb.Period にしたもの（course_periods）
+	Periods []kdb.Period `json:"-"`
+	// CourseNumber を分けたもの（courses.organization_prefix, course_level）
+	// 分けられない科目番号ならゼロ値
+	ParsedCourseNumber kdb.CourseNumber `json:"-"`
+	// Credits を数値にしたもの（courses.credits_numeric）
+	CreditsNumeric kdb.Credits `json:"-"`
+	// Credits が "?" か数値として読めないもの（CreditsNumeric は Valid でない）
+	CreditsUnknown bool `json:"-"`
 }
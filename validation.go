@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	errorFormatTable = "table"
+	errorFormatJSON  = "json"
+)
+
+var (
+	// 変換できない行があった
+	errInvalidRows = errors.New("csv has invalid rows")
+	// --max-errors を超えたため読み込みを打ち切った
+	errTooManyErrors = errors.New("too many errors")
+)
+
+// 1 つのカラムを変換できなかったときのエラー
+// Column は CSV のヘッダー名
+type fieldError struct {
+	Column string
+	Value  string
+	Err    error
+}
+
+// CSV の 1 行で見つかった問題
+type rowError struct {
+	Input        string `json:"input"`
+	Line         int    `json:"line"`
+	CourseNumber string `json:"course_number"`
+	Column       string `json:"column"`
+	Value        string `json:"value"`
+	Message      string `json:"message"`
+}
+
+// 変換できない行があったときにどうするか
+type validationPolicy struct {
+	// この数を超えるエラーが見つかった時点で読み込みを打ち切る（0 は無制限）
+	maxErrors int
+	// 変換できない行を読み飛ばし，残りの行だけを使う
+	skipInvalid bool
+	// エラーの出力形式（errorFormatTable, errorFormatJSON）
+	errorFormat string
+	// 変換できなかった行を書き出す CSV（空の場合は書き出さない）
+	rejectFile string
+}
+
+func addValidationFlags(cmd *cobra.Command, policy *validationPolicy) {
+	f := cmd.Flags()
+	f.IntVar(&policy.maxErrors, "max-errors", 0, "この数を超えるエラーが見つかった時点で読み込みを打ち切る（0 は無制限）")
+	f.BoolVar(&policy.skipInvalid, "skip-invalid", false, "変換できない行を読み飛ばして残りの行を使う")
+	f.StringVar(&policy.errorFormat, "error-format", errorFormatTable, "エラーの出力形式（table, json）")
+	f.StringVar(&policy.rejectFile, "reject-file", "", "変換できなかった行をエラーとともに書き出す CSV（元の CSV と同じ文字コード。CSV が複数なら CSV ごとに分ける）")
+}
+
+// 表示するために手元に残すエラーの数
+// 大きな CSV でエラーが多くてもメモリが増え続けないよう，これを超えた分は数えるだけにする
+const maxKeptErrors = 1000
+
+// 読み込み中に見つかったエラーを集める
+// 変換できなかった行は，--reject-file が指定されていればそのまま書き出して残さない
+type errorCollector struct {
+	policy validationPolicy
+	// 最初の maxKeptErrors 件のエラー
+	errors []rowError
+	// 見つかったエラーの数
+	count   int
+	rejects *rejectWriter
+}
+
+// inputs は読み込む CSV の名前で，reject ファイルはそれぞれに分けて書き出す
+func newErrorCollector(policy validationPolicy, inputs []string) (*errorCollector, error) {
+	c := &errorCollector{policy: policy}
+	if policy.rejectFile != "" {
+		rejects, err := newRejectWriter(policy.rejectFile, inputs)
+		if err != nil {
+			return nil, err
+		}
+		c.rejects = rejects
+	}
+	return c, nil
+}
+
+// 1 行分のエラーを追加し，r を reject ファイルに書き出す
+// --max-errors を超えた場合は errTooManyErrors を返す
+func (c *errorCollector) add(r csvRecord, courseNumber string, fieldErrors []fieldError) error {
+	reject := rejectedRow{Header: r.header, Record: r.record, Encoding: r.encoding}
+	for _, fe := range fieldErrors {
+		reject.Errors = append(reject.Errors, rowError{
+			Input:        r.input,
+			Line:         r.line,
+			CourseNumber: courseNumber,
+			Column:       fe.Column,
+			Value:        fe.Value,
+			Message:      fe.Err.Error(),
+		})
+	}
+	for _, e := range reject.Errors {
+		if len(c.errors) < maxKeptErrors {
+			c.errors = append(c.errors, e)
+		}
+	}
+	c.count += len(reject.Errors)
+	if c.rejects != nil {
+		err := c.rejects.write(reject)
+		if err != nil {
+			return err
+		}
+	}
+	if c.policy.maxErrors > 0 && c.count > c.policy.maxErrors {
+		return errors.Wrapf(errTooManyErrors, "more than %d errors", c.policy.maxErrors)
+	}
+	return nil
+}
+
+// reject ファイルを閉じ，書き出した行数とファイルを返す
+func (c *errorCollector) close() (int, []string, error) {
+	if c.rejects == nil {
+		return 0, nil, nil
+	}
+	return c.rejects.rows, c.rejects.written(), c.rejects.close()
+}
+
+// 見つかったエラーを指定された形式で書き出す
+func writeRowErrors(w io.Writer, format string, rowErrors []rowError) error {
+	switch format {
+	case errorFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return errors.WithStack(enc.Encode(rowErrors))
+	case errorFormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "INPUT\tLINE\tCOURSE_NUMBER\tCOLUMN\tVALUE\tERROR")
+		for _, e := range rowErrors {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%q\t%s\n", e.Input, e.Line, e.CourseNumber, e.Column, e.Value, e.Message)
+		}
+		return errors.WithStack(tw.Flush())
+	default:
+		return errors.Errorf("unknown error format: %s", format)
+	}
+}
+
+func validateErrorFormat(format string) error {
+	switch format {
+	case errorFormatTable, errorFormatJSON:
+		return nil
+	default:
+		return errors.Errorf("unknown error format: %s", format)
+	}
+}
+
+// エラーがあれば w に書き出す
+// 変換できなかった行は読み込み中に reject ファイルに書き出している
+func reportRowErrors(w io.Writer, policy validationPolicy, stats loadStats) error {
+	if len(stats.Errors) == 0 {
+		return nil
+	}
+	err := writeRowErrors(w, policy.errorFormat, stats.Errors)
+	if err != nil {
+		return err
+	}
+	if stats.OmittedErrors > 0 {
+		log.Printf("%d more errors are not shown, use --reject-file to write every invalid row", stats.OmittedErrors)
+	}
+	if stats.Rejected > 0 {
+		log.Printf("wrote %d rejected rows to %s", stats.Rejected, strings.Join(stats.RejectFiles, ", "))
+	}
+	return nil
+}